@@ -0,0 +1,111 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// useCache marks every file that is unchanged in the cache with its sum from the oldManifest, so it isn't hashed again.
+// A file is only skipped if the oldManifest has every algorithm that would have been calculated.
+// The file is found in the oldManifest with the index, so the name can be different depending on the path policy.
+func useCache(done chan struct{}, files chan *pathFileInfo, cache *manifest.Cache, oldManifest *manifest.Manifest, index *manifest.Index, algorithms []string, out chan *pathFileInfo) {
+	defer close(out)
+	for file := range files {
+		manifestName, found := index.Find(file.name)
+		if sum, ok := (*oldManifest)[manifestName]; found && ok && hasAlgorithms(sum, algorithms) && cache.Unchanged(file.name, file.FileInfo) {
+			file.cached = sum
+		}
+		select {
+		case <-done:
+			return
+		case out <- file:
+		}
+	}
+}
+
+// hasAlgorithms returns true if the sum has every one of the algorithms, or the defaults if there are none
+func hasAlgorithms(sum manifest.Sum, algorithms []string) bool {
+	if len(algorithms) == 0 {
+		algorithms = manifest.DefaultAlgorithms
+	}
+	for _, name := range algorithms {
+		if sum[name] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cachePath is where the cache file is, by default it's next to the manifest
+// `cacheFileName` is relative to the working directory, so the cache can be kept outside of a read-only folder
+func (h *folderHasher) cachePath(dirName string) string {
+	if h.cacheFileName != "" {
+		return h.cacheFileName
+	}
+	return path.Join(dirName, h.manifestFileName+".cache")
+}
+
+// excludeNames are the files in dirName that are never hashed, the manifest, the cache and the report
+func (h *folderHasher) excludeNames(dirName string) []string {
+	names := []string{filepath.ToSlash(h.manifestFileName)}
+	for _, fileName := range []string{h.cachePath(dirName), h.reportFileName} {
+		if fileName == "" {
+			continue
+		}
+		if name, ok := inFolder(dirName, fileName); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// inFolder returns the name of the file relative to dirName, with forward slashes like in the manifest, if the file is inside dirName
+func inFolder(dirName, fileName string) (name string, ok bool) {
+	absDir, err := filepath.Abs(dirName)
+	if err != nil {
+		return "", false
+	}
+	absFile, err := filepath.Abs(fileName)
+	if err != nil {
+		return "", false
+	}
+	name, err = filepath.Rel(absDir, absFile)
+	if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(name), true
+}
+
+// loadCache loads the cache in quick mode, a missing cache just means every file is hashed
+func (h *folderHasher) loadCache(dirName string) *manifest.Cache {
+	if !h.quick {
+		return nil
+	}
+	cache := &manifest.Cache{}
+	if err := cache.Load(h.cachePath(dirName)); err != nil {
+		h.infoLog.Println("Warning:", err)
+		h.infoLog.Println("Hashing every file.")
+	}
+	return cache
+}
+
+// saveCache saves the newCache in quick or full mode, unless it would be written inside the folder in verify mode
+func (h *folderHasher) saveCache(dirName string, newCache *manifest.Cache) error {
+	if !h.quick && !h.full {
+		return nil
+	}
+	if _, ok := inFolder(dirName, h.cachePath(dirName)); ok && h.verifyOnly {
+		// verify mode never writes in the folder, which may be read-only
+		h.infoLog.Println("Warning: the cache isn't saved inside the folder in verify mode, use -cache to keep it somewhere else.")
+		return nil
+	}
+	if err := newCache.Save(h.cachePath(dirName)); err != nil {
+		return fmt.Errorf("Error saving cache %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_hashFolder_Quick(t *testing.T) {
+	// GIVEN a folder with a file
+	dirName := t.TempDir()
+	fileName := path.Join(dirName, "a.txt")
+	if err := ioutil.WriteFile(fileName, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN the folder is hashed in full mode
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.full = true
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the cache should be saved next to the manifest
+	if _, err := os.Stat(path.Join(dirName, "manifest.json.cache")); err != nil {
+		t.Fatalf("The cache should have been saved: %v", err)
+	}
+
+	// WHEN the folder is hashed in quick mode
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.quick = true
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the file should come from the cache, and the cache itself should not be hashed
+	is := infoBuffer.String()
+	if !strings.Contains(is, "Checked 1 files (1 unchanged in cache)") {
+		t.Errorf("a.txt should have been unchanged in the cache: %v", is)
+	}
+
+	// WHEN the file changes
+	if err := ioutil.WriteFile(fileName, []byte("bb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(fileName, later, later); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.quick = true
+	err := h.HashFolder(dirName)
+
+	// THEN the file should be hashed again and fail
+	if err == nil {
+		t.Error("a.txt changed, and should have failed")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "a.txt") {
+		t.Errorf("The error log should have a.txt: %v", es)
+	}
+}
+
+func Test_hashFolder_VerifyQuick(t *testing.T) {
+	// GIVEN a folder with a manifest
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// WHEN it is verified in quick mode
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.quick = true
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN nothing is written in the folder
+	if _, err := os.Stat(path.Join(dirName, "manifest.json.cache")); !os.IsNotExist(err) {
+		t.Errorf("The cache shouldn't be saved in the folder: %v", err)
+	}
+
+	// WHEN the cache is outside of the folder
+	cacheFileName := path.Join(t.TempDir(), "archive.cache")
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.quick = true
+	h.cacheFileName = cacheFileName
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN it is saved
+	if _, err := os.Stat(cacheFileName); err != nil {
+		t.Errorf("The cache should be saved outside of the folder: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// command is run instead of hashing the folder when its name is after the flags, ex. `VerifyManifest -root photos duplicates`
+type command struct {
+	name        string
+	description string
+	run         func(h *folderHasher, dirName string, args []string) int // returns the exit code
+}
+
+// commands in the order they are shown in the usage
+var commands = []command{
+	{"duplicates", "Find files with the same contents, from the folder or with -from-manifest from the manifest.", duplicatesCommand},
+	{"diff", "Compare two manifest files, ex. diff old.json new.json, without reading the folder.", diffCommand},
+	{"compare", "Compare the files in two folders, ex. compare /mnt/backup, without writing a manifest.", compareCommand},
+	{"update", "Save the manifest even when files changed or are missing, and log every change.  Use -accept to only allow some files to change.", updateCommand},
+}
+
+// runCommand runs the command on the root folder with its own flags in `args`, and returns the exit code
+func runCommand(h *folderHasher, dirName, name string, args []string) int {
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(h, dirName, args)
+		}
+	}
+	h.errorLog.Printf("Unknown command %v", name)
+	return exitUsage
+}
+
+// printCommands writes the list of commands for the usage
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %v\n    \t%v\n", c.name, c.description)
+	}
+}
+
+// newCommandFlags makes the flag set for a command, errors are returned instead of exiting
+func newCommandFlags(h *folderHasher, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(h.errorLog.Writer())
+	return flags
+}
@@ -0,0 +1,171 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"sort"
+)
+
+// compareResult is how the files in a backup folder are different from the source folder
+type compareResult struct {
+	Source    string          `json:"source"`
+	Backup    string          `json:"backup"`
+	Different []*changedEntry `json:"different"`
+	Missing   []string        `json:"missing"` // only in the source
+	Extra     []string        `json:"extra"`   // only in the backup
+	Errors    []*compareError `json:"errors"`
+	Same      int             `json:"same"`
+}
+
+// compareError is a file that couldn't be read in one of the folders
+type compareError struct {
+	Name   string `json:"name"`
+	Folder string `json:"folder"`
+	Error  string `json:"error"`
+}
+
+// exitCode is the first of changed, missing or an error, like when verifying a manifest
+func (r *compareResult) exitCode() int {
+	switch {
+	case len(r.Different) > 0:
+		return exitChanged
+	case len(r.Missing) > 0 || len(r.Extra) > 0:
+		return exitMissing
+	case len(r.Errors) > 0:
+		return exitIO
+	}
+	return exitOK
+}
+
+// compareCommand hashes two folders at the same time and compares the files with the same names, no manifest is written
+func compareCommand(h *folderHasher, dirName string, args []string) int {
+	flags := newCommandFlags(h, "compare")
+	asJSON := flags.Bool("json", false, "Write the differences as JSON to stdout.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	sourceDir := dirName
+	switch flags.NArg() {
+	case 1:
+	case 2:
+		sourceDir = flags.Arg(0)
+	default:
+		h.errorLog.Print("compare needs the backup folder, ex. compare /mnt/backup, or both folders, ex. compare src /mnt/backup")
+		return exitUsage
+	}
+	backupDir := flags.Arg(flags.NArg() - 1)
+
+	result, err := h.compareFolders(sourceDir, backupDir)
+	if err != nil {
+		h.errorLog.Print(err)
+		return exitCode(err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(h.reportOutput)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(result); err != nil {
+			h.errorLog.Print(err)
+			return exitIO
+		}
+	} else {
+		h.logCompare(result)
+	}
+	return result.exitCode()
+}
+
+// compareFolders hashes both folders concurrently, then matches the files by their path in each folder
+func (h *folderHasher) compareFolders(sourceDir, backupDir string) (*compareResult, error) {
+	type hashed struct {
+		files []*fileNameSum
+		err   error
+	}
+	sourceHashed := make(chan hashed, 1)
+	go func() {
+		files, err := h.hashFolderFiles(sourceDir)
+		sourceHashed <- hashed{files, err}
+	}()
+	backupFiles, backupErr := h.hashFolderFiles(backupDir)
+	source := <-sourceHashed
+	if source.err != nil {
+		return nil, source.err
+	}
+	if backupErr != nil {
+		return nil, backupErr
+	}
+
+	result := &compareResult{
+		Source:    sourceDir,
+		Backup:    backupDir,
+		Different: []*changedEntry{},
+		Missing:   []string{},
+		Extra:     []string{},
+		Errors:    []*compareError{},
+	}
+	sourceSums := manifest.Manifest{}
+	for _, f := range source.files {
+		if f.Err != nil {
+			result.Errors = append(result.Errors, &compareError{Name: f.FileName, Folder: sourceDir, Error: f.Err.Error()})
+		}
+		sourceSums[f.FileName] = f.Sum
+	}
+	index := sourceSums.Index(h.pathPolicy)
+	matched := map[string]bool{}
+	for _, f := range backupFiles {
+		sourceName, ok := index.Find(f.FileName)
+		if ok {
+			matched[sourceName] = true
+		}
+		if f.Err != nil {
+			// a file that can't be read is an error, even if it's only in the backup
+			result.Errors = append(result.Errors, &compareError{Name: f.FileName, Folder: backupDir, Error: f.Err.Error()})
+			continue
+		}
+		if !ok {
+			result.Extra = append(result.Extra, f.FileName)
+			continue
+		}
+		sourceSum := sourceSums[sourceName]
+		if len(sourceSum) == 0 {
+			// the source file couldn't be read, it's already an error
+			continue
+		}
+		if err := sourceSum.Verify(f.Sum); err != nil {
+			result.Different = append(result.Different, &changedEntry{Name: sourceName, Algorithms: diffAlgorithms(sourceSum, f.Sum)})
+			continue
+		}
+		result.Same++
+	}
+	for fileName := range sourceSums {
+		if !matched[fileName] {
+			result.Missing = append(result.Missing, fileName)
+		}
+	}
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	sort.Slice(result.Different, func(i, j int) bool { return result.Different[i].Name < result.Different[j].Name })
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Name < result.Errors[j].Name })
+	return result, nil
+}
+
+// logCompare writes the differences for people to read
+func (h *folderHasher) logCompare(result *compareResult) {
+	for _, fileName := range result.Missing {
+		h.errorLog.Printf("Missing %v was in %v, but not found in %v\n", fileName, result.Source, result.Backup)
+	}
+	for _, fileName := range result.Extra {
+		h.errorLog.Printf("Extra %v was in %v, but not found in %v\n", fileName, result.Backup, result.Source)
+	}
+	for _, different := range result.Different {
+		h.errorLog.Printf("Different %v\n", different.Name)
+		for _, a := range different.Algorithms {
+			h.errorLog.Printf("\t%v\n", a)
+		}
+	}
+	for _, e := range result.Errors {
+		h.errorLog.Printf("Error reading %v in %v: %v\n", e.Name, e.Folder, e.Error)
+	}
+	h.infoLog.Printf("%d same, %d different, %d missing, %d extra, %d errors\n", result.Same, len(result.Different), len(result.Missing), len(result.Extra), len(result.Errors))
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeCompareFolders makes a source and backup folder, where a is the same, b is different, c is only in the source and d is only in the backup
+func makeCompareFolders(t *testing.T) (sourceDir, backupDir string) {
+	sourceDir, backupDir = t.TempDir(), t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{"a.txt": "a", "sub/b.txt": "b", "c.txt": "c"})
+	writeTestFiles(t, backupDir, map[string]string{"a.txt": "a", "sub/b.txt": "not b", "d.txt": "d"})
+	return
+}
+
+func Test_compareCommand(t *testing.T) {
+	// GIVEN a source and a backup folder that are different
+	sourceDir, backupDir := makeCompareFolders(t)
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN the folders are compared
+	code := compareCommand(h, sourceDir, []string{backupDir})
+
+	// THEN the content mismatch is reported first
+	if code != exitChanged {
+		t.Errorf("Expected exit code %d got %d", exitChanged, code)
+	}
+	errors := errorBuffer.String()
+	for _, line := range []string{
+		"Missing c.txt was in " + sourceDir,
+		"Extra d.txt was in " + backupDir,
+		"Different sub/b.txt",
+	} {
+		if !strings.Contains(errors, line) {
+			t.Errorf("Expected %q in %v", line, errors)
+		}
+	}
+	if info := infoBuffer.String(); !strings.Contains(info, "1 same, 1 different, 1 missing, 1 extra, 0 errors") {
+		t.Errorf("Expected the summary got %v", info)
+	}
+
+	// AND no manifest is written in either folder
+	for _, dirName := range []string{sourceDir, backupDir} {
+		if _, err := os.Stat(filepath.Join(dirName, "manifest.json")); !os.IsNotExist(err) {
+			t.Errorf("A manifest shouldn't be written in %v", dirName)
+		}
+	}
+}
+
+func Test_compareCommand_JSON(t *testing.T) {
+	// GIVEN a source and a backup folder that are different
+	sourceDir, backupDir := makeCompareFolders(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	output := &bytes.Buffer{}
+	h.reportOutput = output
+
+	// WHEN both folders are given and compared as JSON
+	compareCommand(h, ".", []string{"-json", sourceDir, backupDir})
+
+	// THEN the document has the differences
+	var result compareResult
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil {
+		t.Fatal(err, output, errorBuffer)
+	}
+	if result.Same != 1 || len(result.Different) != 1 || strings.Join(result.Missing, ",") != "c.txt" || strings.Join(result.Extra, ",") != "d.txt" {
+		t.Errorf("Expected a same, b different, c missing and d extra got %v", output)
+	}
+}
+
+func Test_compareCommand_Same(t *testing.T) {
+	// GIVEN a folder
+	sourceDir, _ := makeCompareFolders(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN it's compared to itself
+	code := compareCommand(h, sourceDir, []string{sourceDir})
+
+	// THEN there are no differences
+	if code != exitOK {
+		t.Errorf("Expected exit code %d got %d %v", exitOK, code, errorBuffer)
+	}
+}
+
+func Test_compareCommand_ExtraReadError(t *testing.T) {
+	// GIVEN a backup folder with a file that can't be read, and isn't in the source folder
+	sourceDir, backupDir := t.TempDir(), t.TempDir()
+	writeTestFiles(t, sourceDir, map[string]string{"a.txt": "a"})
+	writeTestFiles(t, backupDir, map[string]string{"a.txt": "a"})
+	listener, err := net.Listen("unix", filepath.Join(backupDir, "socket"))
+	if err != nil {
+		t.Skip("Unix sockets aren't supported:", err)
+	}
+	defer listener.Close()
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN the folders are compared
+	code := compareCommand(h, sourceDir, []string{backupDir})
+
+	// THEN the file is an error and not extra
+	if code != exitIO {
+		t.Errorf("Expected exit code %d got %d", exitIO, code)
+	}
+	if errors := errorBuffer.String(); !strings.Contains(errors, "Error reading socket in "+backupDir) || strings.Contains(errors, "Extra socket") {
+		t.Errorf("Expected only an error reading the socket: %v", errors)
+	}
+	if info := infoBuffer.String(); !strings.Contains(info, "1 same, 0 different, 0 missing, 0 extra, 1 errors") {
+		t.Errorf("Expected the summary got %v", info)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"strings"
+)
+
+// manifestDiff is how a new manifest is different from an old one
+type manifestDiff struct {
+	Added     []string        `json:"added"`
+	Removed   []string        `json:"removed"`
+	Changed   []*changedEntry `json:"changed"`
+	Moved     []*movedEntry   `json:"moved"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// changedEntry is a file in both manifests whose hashes or attributes don't match
+type changedEntry struct {
+	Name       string           `json:"name"`
+	Algorithms []*algorithmDiff `json:"algorithms"`
+}
+
+// algorithmDiff compares one algorithm's hash, Old or New is empty if only one manifest has it
+type algorithmDiff struct {
+	Algorithm string `json:"algorithm"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+	Match     bool   `json:"match"`
+}
+
+func (a *algorithmDiff) String() string {
+	switch {
+	case a.Match:
+		return fmt.Sprintf("%v %v", a.Algorithm, a.Old)
+	case a.Old == "":
+		return fmt.Sprintf("%v only in new %v", a.Algorithm, a.New)
+	case a.New == "":
+		return fmt.Sprintf("%v only in old %v", a.Algorithm, a.Old)
+	}
+	return fmt.Sprintf("%v %v != %v", a.Algorithm, a.Old, a.New)
+}
+
+// movedEntry is a file that has a different name in the new manifest, but the same hashes
+type movedEntry struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+}
+
+// same returns true if there are no differences
+func (d *manifestDiff) same() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.Moved) == 0
+}
+
+// diffManifests compares every file in the new manifest to the old manifest, names are matched with the path policy
+func diffManifests(oldManifest, newManifest *manifest.Manifest, pathPolicy manifest.PathPolicy) *manifestDiff {
+	diff := &manifestDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []*changedEntry{},
+		Moved:   []*movedEntry{},
+	}
+	index := oldManifest.Index(pathPolicy)
+	matched := map[string]bool{}
+	var added []string
+	for _, fileName := range newManifest.FileNames() {
+		sum := (*newManifest)[fileName]
+		oldName, ok := index.Find(fileName)
+		if !ok {
+			added = append(added, fileName)
+			continue
+		}
+		matched[oldName] = true
+		if !sameSum((*oldManifest)[oldName], sum) {
+			diff.Changed = append(diff.Changed, &changedEntry{Name: fileName, Algorithms: diffAlgorithms((*oldManifest)[oldName], sum)})
+			continue
+		}
+		diff.Unchanged++
+	}
+	digests := newDigestIndex(oldManifest, matched)
+	for _, fileName := range added {
+		if oldName, ok := digests.find((*newManifest)[fileName]); ok {
+			matched[oldName] = true
+			diff.Moved = append(diff.Moved, &movedEntry{Name: fileName, From: oldName})
+			continue
+		}
+		diff.Added = append(diff.Added, fileName)
+	}
+	for _, fileName := range oldManifest.FileNames() {
+		if !matched[fileName] {
+			diff.Removed = append(diff.Removed, fileName)
+		}
+	}
+	return diff
+}
+
+// sameSum returns true if the hashes match, and any metadata attributes both sums have are the same
+func sameSum(oldSum, newSum manifest.Sum) bool {
+	if err := oldSum.Verify(newSum); err != nil {
+		return false
+	}
+	return oldSum.VerifyAttributes(newSum, manifest.MetadataAttributes...) == nil
+}
+
+// diffAlgorithms compares every algorithm in either sum, and any attributes that are different
+func diffAlgorithms(oldSum, newSum manifest.Sum) []*algorithmDiff {
+	names := oldSum.Algorithms()
+	for _, name := range newSum.Algorithms() {
+		if _, ok := oldSum[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range append(oldSum.Attributes(), newSum.Attributes()...) {
+		if oldSum[name] != newSum[name] && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	diffs := make([]*algorithmDiff, 0, len(names))
+	for _, name := range names {
+		diffs = append(diffs, &algorithmDiff{
+			Algorithm: name,
+			Old:       oldSum[name],
+			New:       newSum[name],
+			Match:     oldSum[name] != "" && strings.EqualFold(oldSum[name], newSum[name]),
+		})
+	}
+	return diffs
+}
+
+// containsString returns true if the list has the string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// diffCommand compares two manifest files, without reading the folder
+func diffCommand(h *folderHasher, dirName string, args []string) int {
+	flags := newCommandFlags(h, "diff")
+	asJSON := flags.Bool("json", false, "Write the differences as JSON to stdout.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 2 {
+		h.errorLog.Print("diff needs two manifest files, ex. diff old.json new.json")
+		return exitUsage
+	}
+	oldManifest, newManifest := &manifest.Manifest{}, &manifest.Manifest{}
+	if err := oldManifest.Load("", flags.Arg(0)); err != nil {
+		h.errorLog.Print(err)
+		return exitIO
+	}
+	if err := newManifest.Load("", flags.Arg(1)); err != nil {
+		h.errorLog.Print(err)
+		return exitIO
+	}
+	diff := diffManifests(oldManifest, newManifest, h.pathPolicy)
+
+	if *asJSON {
+		encoder := json.NewEncoder(h.reportOutput)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(diff); err != nil {
+			h.errorLog.Print(err)
+			return exitIO
+		}
+	} else {
+		h.logDiff(diff)
+	}
+	if !diff.same() {
+		return exitChanged
+	}
+	return exitOK
+}
+
+// logDiff writes the differences for people to read
+func (h *folderHasher) logDiff(diff *manifestDiff) {
+	for _, fileName := range diff.Added {
+		h.infoLog.Printf("Added %v\n", fileName)
+	}
+	for _, fileName := range diff.Removed {
+		h.infoLog.Printf("Removed %v\n", fileName)
+	}
+	for _, changed := range diff.Changed {
+		h.infoLog.Printf("Changed %v\n", changed.Name)
+		for _, a := range changed.Algorithms {
+			h.infoLog.Printf("\t%v\n", a)
+		}
+	}
+	for _, moved := range diff.Moved {
+		h.infoLog.Printf("Moved %v from %v\n", moved.Name, moved.From)
+	}
+	h.infoLog.Printf("%d added, %d removed, %d changed, %d moved, %d unchanged\n", len(diff.Added), len(diff.Removed), len(diff.Changed), len(diff.Moved), diff.Unchanged)
+}
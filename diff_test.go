@@ -0,0 +1,144 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeDiffManifests saves an old and new manifest, where a is the same, b changed, c was added, d was removed and e moved to f
+func makeDiffManifests(t *testing.T) (oldFileName, newFileName string) {
+	dirName := t.TempDir()
+	oldManifest := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"b.txt": manifest.Sum{manifest.MD5: "92eb5ffee6ae2fec3ad71c777531578f", manifest.SHA1: "e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98"},
+		"d.txt": manifest.Sum{manifest.MD5: "8277e0910d750195b448797616e091ad"},
+		"e.txt": manifest.Sum{manifest.MD5: "e1671797c52e15f763380b45e841ec32"},
+	}
+	newManifest := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"b.txt": manifest.Sum{manifest.MD5: "4a8a08f09d37b73795649038408b5f33", manifest.SHA1: "e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98"},
+		"c.txt": manifest.Sum{manifest.MD5: "4a8a08f09d37b73795649038408b5f33"},
+		"f.txt": manifest.Sum{manifest.MD5: "e1671797c52e15f763380b45e841ec32"},
+	}
+	oldFileName = filepath.Join(dirName, "old.json")
+	newFileName = filepath.Join(dirName, "new.json")
+	if err := oldManifest.Save(dirName, "old.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newManifest.Save(dirName, "new.json"); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func Test_diffCommand(t *testing.T) {
+	// GIVEN two different manifests
+	oldFileName, newFileName := makeDiffManifests(t)
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN they are compared
+	code := diffCommand(h, "", []string{oldFileName, newFileName})
+
+	// THEN every difference is logged
+	if code != exitChanged {
+		t.Errorf("Expected exit code %d got %d %v", exitChanged, code, errorBuffer)
+	}
+	info := infoBuffer.String()
+	for _, line := range []string{
+		"Added c.txt",
+		"Removed d.txt",
+		"Changed b.txt",
+		"\tMD5 92eb5ffee6ae2fec3ad71c777531578f != 4a8a08f09d37b73795649038408b5f33",
+		"\tSHA1 e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98",
+		"Moved f.txt from e.txt",
+		"1 added, 1 removed, 1 changed, 1 moved, 1 unchanged",
+	} {
+		if !strings.Contains(info, line) {
+			t.Errorf("Expected %q in %v", line, info)
+		}
+	}
+}
+
+func Test_diffCommand_JSON(t *testing.T) {
+	// GIVEN two different manifests
+	oldFileName, newFileName := makeDiffManifests(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	output := &bytes.Buffer{}
+	h.reportOutput = output
+
+	// WHEN they are compared as JSON
+	diffCommand(h, "", []string{"-json", oldFileName, newFileName})
+
+	// THEN the document has each algorithm of the changed file
+	var diff manifestDiff
+	if err := json.Unmarshal(output.Bytes(), &diff); err != nil {
+		t.Fatal(err, output, errorBuffer)
+	}
+	if len(diff.Changed) != 1 || len(diff.Changed[0].Algorithms) != 2 {
+		t.Fatalf("Expected b.txt with 2 algorithms got %v", output)
+	}
+	for _, a := range diff.Changed[0].Algorithms {
+		if a.Match != (a.Algorithm == manifest.SHA1) {
+			t.Errorf("Only sha1 should match %+v", a)
+		}
+	}
+	if len(diff.Moved) != 1 || diff.Moved[0].From != "e.txt" || diff.Unchanged != 1 {
+		t.Errorf("Expected f.txt moved from e.txt got %v", output)
+	}
+}
+
+func Test_diffCommand_Same(t *testing.T) {
+	// GIVEN a manifest
+	oldFileName, _ := makeDiffManifests(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN it is compared to itself
+	code := diffCommand(h, "", []string{oldFileName, oldFileName})
+
+	// THEN there are no differences
+	if code != exitOK {
+		t.Errorf("Expected exit code %d got %d %v", exitOK, code, errorBuffer)
+	}
+}
+
+func Test_diffCommand_Attributes(t *testing.T) {
+	// GIVEN two manifests where a file has the same hash, but a different mode
+	dirName := t.TempDir()
+	oldManifest := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661", manifest.Mode: "0644", manifest.Size: "1"},
+	}
+	newManifest := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661", manifest.Mode: "0755", manifest.Size: "1"},
+	}
+	if err := oldManifest.Save(dirName, "old.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newManifest.Save(dirName, "new.json"); err != nil {
+		t.Fatal(err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN they are compared
+	code := diffCommand(h, "", []string{filepath.Join(dirName, "old.json"), filepath.Join(dirName, "new.json")})
+
+	// THEN the file is changed because of its mode
+	if code != exitChanged {
+		t.Errorf("Expected exit code %d got %d %v", exitChanged, code, errorBuffer)
+	}
+	info := infoBuffer.String()
+	for _, line := range []string{
+		"Changed a.txt",
+		"\tmode 0644 != 0755",
+		"0 added, 0 removed, 1 changed, 0 moved, 0 unchanged",
+	} {
+		if !strings.Contains(info, line) {
+			t.Errorf("Expected %q in %v", line, info)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_hashFolder_Dirs(t *testing.T) {
+	// GIVEN a folder with an empty folder and a folder with a file
+	dirName := t.TempDir()
+	for _, name := range []string{"empty", "full"} {
+		if err := os.Mkdir(filepath.Join(dirName, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirName, "full", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN it is hashed recording folders
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+	h.dirs = true
+	h.meta = []string{manifest.Mode}
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the folders are in the manifest
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"empty", "full"} {
+		if !m[name].IsDir() || m[name][manifest.Mode] == "" {
+			t.Errorf("%v should be a folder with a mode: %v", name, m[name])
+		}
+	}
+
+	// WHEN the empty folder is removed, and another is added
+	if err := os.Remove(filepath.Join(dirName, "empty")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dirName, "added"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.dirs = true
+	err := h.HashFolder(dirName)
+
+	// THEN the removed folder fails, and the new one is reported
+	if err == nil {
+		t.Error("A removed folder should fail")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Missing folder empty") {
+		t.Errorf("Expected the folder to be missing: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "New folder added") {
+		t.Errorf("Expected the folder to be new: %v", is)
+	}
+
+	// WHEN the folders aren't checked
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// THEN the folders in the manifest are ignored
+	if err := h.HashFolder(dirName); err != nil {
+		t.Error("Folders should only be checked with -dirs", err, errorBuffer)
+	}
+}
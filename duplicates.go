@@ -0,0 +1,168 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// duplicateGroup is files that all have the same hashes
+type duplicateGroup struct {
+	Sum         manifest.Sum `json:"sum"`
+	Size        int64        `json:"size"` // the size of one of the files, or -1 if it isn't known
+	Files       []string     `json:"files"`
+	Reclaimable int64        `json:"reclaimable"` // the space saved by keeping only one of the files
+}
+
+// duplicatesReport is every group of duplicates, the most space that can be reclaimed first
+type duplicatesReport struct {
+	Groups      []*duplicateGroup `json:"groups"`
+	Files       int               `json:"files"` // how many files are duplicates of another file
+	Reclaimable int64             `json:"reclaimable"`
+}
+
+// duplicatesCommand finds files with the same contents in the root folder, or in its manifest with -from-manifest
+func duplicatesCommand(h *folderHasher, dirName string, args []string) int {
+	flags := newCommandFlags(h, "duplicates")
+	fromManifest := flags.Bool("from-manifest", false, "Use the hashes in the manifest instead of hashing every file.")
+	asJSON := flags.Bool("json", false, "Write the groups as JSON to stdout.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var sums manifest.Manifest
+	var sizes map[string]int64
+	var err error
+	if *fromManifest {
+		sums, sizes, err = h.manifestSizes(dirName)
+	} else {
+		sums, sizes, err = h.folderSizes(dirName)
+	}
+	if err != nil {
+		h.errorLog.Print(err)
+		return exitCode(err)
+	}
+	report := findDuplicates(sums, sizes)
+
+	if *asJSON {
+		encoder := json.NewEncoder(h.reportOutput)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(report); err != nil {
+			h.errorLog.Print(err)
+			return exitIO
+		}
+		return exitOK
+	}
+	for _, group := range report.Groups {
+		h.infoLog.Printf("%d files of %v, %v reclaimable\t%v\n", len(group.Files), formatSize(group.Size), formatSize(group.Reclaimable), group.Sum)
+		for _, fileName := range group.Files {
+			h.infoLog.Printf("\t%v\n", fileName)
+		}
+	}
+	h.infoLog.Printf("Found %d duplicate files in %d groups, %v reclaimable\n", report.Files, len(report.Groups), formatSize(report.Reclaimable))
+	return exitOK
+}
+
+// folderSizes hashes every file in the folder, files that can't be read are logged and skipped
+func (h *folderHasher) folderSizes(dirName string) (manifest.Manifest, map[string]int64, error) {
+	files, err := h.hashFolderFiles(dirName)
+	if err != nil {
+		return nil, nil, err
+	}
+	sums := manifest.Manifest{}
+	sizes := map[string]int64{}
+	for _, f := range files {
+		if f.Err != nil {
+			h.errorLog.Printf("Error reading %v: %v\n", f.FileName, f.Err)
+			continue
+		}
+		sums[f.FileName] = f.Sum
+		sizes[f.FileName] = f.Info.Size()
+	}
+	return sums, sizes, nil
+}
+
+// manifestSizes loads the manifest, the sizes are from the manifest's size attribute if it has one, or from the files
+func (h *folderHasher) manifestSizes(dirName string) (manifest.Manifest, map[string]int64, error) {
+	sums := manifest.Manifest{}
+	if err := sums.Load(dirName, h.manifestFileName); err != nil {
+		return nil, nil, err
+	}
+	sizes := map[string]int64{}
+	for fileName, sum := range sums {
+		if size, err := strconv.ParseInt(sum[manifest.Size], 10, 64); err == nil {
+			sizes[fileName] = size
+		} else if info, err := os.Stat(filepath.Join(dirName, filepath.FromSlash(fileName))); err == nil {
+			sizes[fileName] = info.Size()
+		} else {
+			sizes[fileName] = -1
+		}
+	}
+	return sums, sizes, nil
+}
+
+// findDuplicates groups the files with the same hashes, folders and links are never duplicates
+func findDuplicates(sums manifest.Manifest, sizes map[string]int64) *duplicatesReport {
+	groups := map[string]*duplicateGroup{}
+	for fileName, sum := range sums {
+		hashes := sum.Hashes()
+		if len(hashes) == 0 {
+			continue
+		}
+		key := hashes.String()
+		group, ok := groups[key]
+		if !ok {
+			group = &duplicateGroup{Sum: hashes, Size: sizes[fileName]}
+			groups[key] = group
+		}
+		if group.Size < 0 {
+			group.Size = sizes[fileName]
+		}
+		group.Files = append(group.Files, fileName)
+	}
+	report := &duplicatesReport{Groups: []*duplicateGroup{}}
+	for _, group := range groups {
+		if len(group.Files) < 2 {
+			continue
+		}
+		sort.Strings(group.Files)
+		if group.Size > 0 {
+			group.Reclaimable = group.Size * int64(len(group.Files)-1)
+		}
+		report.Groups = append(report.Groups, group)
+		report.Files += len(group.Files) - 1
+		report.Reclaimable += group.Reclaimable
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Reclaimable != b.Reclaimable {
+			return a.Reclaimable > b.Reclaimable
+		}
+		return a.Files[0] < b.Files[0]
+	})
+	return report
+}
+
+// formatSize formats a number of bytes for people, ex. "1.5 MiB"
+func formatSize(size int64) string {
+	if size < 0 {
+		return "unknown size"
+	}
+	if size < 1024 {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	value := float64(size) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %v", value, units[unit])
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeDuplicatesFolder makes a folder with three files that are the same, two others that are the same, and one that's different
+func makeDuplicatesFolder(t *testing.T) string {
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{
+		"a.txt":     "same",
+		"sub/b.txt": "same",
+		"sub/c.txt": "same",
+		"d.txt":     "other",
+		"e.txt":     "again",
+		"f.txt":     "again",
+	})
+	return dirName
+}
+
+func Test_duplicatesCommand_JSON(t *testing.T) {
+	// GIVEN a folder with duplicates
+	dirName := makeDuplicatesFolder(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	output := &bytes.Buffer{}
+	h.reportOutput = output
+
+	// WHEN the duplicates are found as JSON
+	if code := duplicatesCommand(h, dirName, []string{"-json"}); code != exitOK {
+		t.Fatal(code, errorBuffer)
+	}
+
+	// THEN the groups are sorted by the space they take
+	var report duplicatesReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatal(err, output)
+	}
+	if len(report.Groups) != 2 || report.Files != 3 || report.Reclaimable != 13 {
+		t.Fatalf("Expected 2 groups with 3 duplicates and 13 bytes got %v", output)
+	}
+	if files := strings.Join(report.Groups[0].Files, ","); files != "a.txt,sub/b.txt,sub/c.txt" || report.Groups[0].Size != 4 {
+		t.Errorf("Expected the 3 files of 4 bytes first got %v", report.Groups[0])
+	}
+	if files := strings.Join(report.Groups[1].Files, ","); files != "e.txt,f.txt" {
+		t.Errorf("Expected e.txt and f.txt got %v", files)
+	}
+}
+
+func Test_duplicatesCommand_FromManifest(t *testing.T) {
+	// GIVEN a manifest with sizes, and a duplicate that was deleted since
+	dirName := makeDuplicatesFolder(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.meta = []string{manifest.Size}
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+	if err := os.Remove(filepath.Join(dirName, "f.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN the duplicates are found from the manifest
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	if code := duplicatesCommand(h, dirName, []string{"-from-manifest"}); code != exitOK {
+		t.Fatal(code, errorBuffer)
+	}
+
+	// THEN the deleted file is still a duplicate
+	is := infoBuffer.String()
+	if !strings.Contains(is, "2 files of 5 bytes, 5 bytes reclaimable") || !strings.Contains(is, "\tf.txt") {
+		t.Errorf("Expected f.txt from the manifest: %v", is)
+	}
+	if !strings.Contains(is, "Found 3 duplicate files in 2 groups, 13 bytes reclaimable") {
+		t.Errorf("Expected a summary: %v", is)
+	}
+}
+
+func Test_duplicatesCommand_FromLegacyManifest(t *testing.T) {
+	// GIVEN a manifest made on Windows by an older version, with backslashes and without sizes
+	dirName := makeDuplicatesFolder(t)
+	m := manifest.Manifest{
+		"a.txt":      manifest.Sum{manifest.MD5: "51037a4a37730f52c8732586d3aaa316"},
+		"sub\\b.txt": manifest.Sum{manifest.MD5: "51037a4a37730f52c8732586d3aaa316"},
+	}
+	if err := m.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	output := &bytes.Buffer{}
+	h.reportOutput = output
+
+	// WHEN the duplicates are found from the manifest
+	if code := duplicatesCommand(h, dirName, []string{"-from-manifest", "-json"}); code != exitOK {
+		t.Fatal(code, errorBuffer)
+	}
+
+	// THEN the file names use forward slashes, and the sizes are from the files
+	var report duplicatesReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatal(err, output)
+	}
+	if len(report.Groups) != 1 || strings.Join(report.Groups[0].Files, ",") != "a.txt,sub/b.txt" || report.Groups[0].Size != 4 {
+		t.Errorf("Expected a.txt and sub/b.txt of 4 bytes got %v", output)
+	}
+}
+
+func Test_duplicatesCommand_BadFlag(t *testing.T) {
+	// WHEN the command has a flag it doesn't know
+	_, _, h := makeTestFolderHasher("manifest.json", "")
+	code := duplicatesCommand(h, t.TempDir(), []string{"-nope"})
+
+	// THEN it is a usage error
+	if code != exitUsage {
+		t.Errorf("Expected %d got %d", exitUsage, code)
+	}
+}
+
+func Test_runCommand_Unknown(t *testing.T) {
+	// WHEN a command doesn't exist
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	code := runCommand(h, t.TempDir(), "nope", nil)
+
+	// THEN it is a usage error
+	if code != exitUsage || !strings.Contains(errorBuffer.String(), "Unknown command nope") {
+		t.Errorf("Expected a usage error got %d %v", code, errorBuffer)
+	}
+}
+
+func Test_formatSize(t *testing.T) {
+	tests := map[int64]string{
+		-1:               "unknown size",
+		0:                "0 bytes",
+		1023:             "1023 bytes",
+		1536:             "1.5 KiB",
+		5 * 1024 * 1024:  "5.0 MiB",
+		3 << 40:          "3.0 TiB",
+		2048 * (1 << 40): "2048.0 TiB",
+	}
+	for size, expected := range tests {
+		if s := formatSize(size); s != expected {
+			t.Errorf("Expected %v to be %q got %q", size, expected, s)
+		}
+	}
+}
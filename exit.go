@@ -0,0 +1,59 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import "errors"
+
+// Exit codes of the command.
+// When several kinds of files fail the code is the first of changed, missing, I/O error, metadata then new.
+const (
+	exitOK       = 0 // every file matched the manifest
+	exitChanged  = 1 // a file's contents changed, or an -unknown hash wasn't found
+	exitUsage    = 2 // a flag or pattern was wrong, or the -unknown file couldn't be read, nothing was checked
+	exitMissing  = 3 // a file in the manifest wasn't found, or was moved without -accept-moves
+	exitNew      = 4 // a file wasn't in the manifest, only with -strict
+	exitIO       = 5 // a file or folder couldn't be read, or the manifest couldn't be loaded or saved
+	exitMetadata = 6 // a file's contents matched, but the metadata checked with -check-meta didn't
+)
+
+// exitError is an error that knows which exit code it should have
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// exitCode returns the exit code for an error returned by HashFolder, errors that aren't an exitError are I/O errors
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitIO
+}
+
+// exitCode returns the exit code for the first kind of failure in the result, or exitOK if nothing failed
+func (r *verifyResult) exitCode(strict bool) int {
+	if len(r.Unknown) > 0 || r.failures(statusChanged, strict) > 0 {
+		return exitChanged
+	}
+	if r.failures(statusMissing, strict) > 0 || r.failures(statusMoved, strict) > 0 {
+		return exitMissing
+	}
+	if r.failures(statusError, strict) > 0 {
+		return exitIO
+	}
+	if r.failures(statusMetadata, strict) > 0 {
+		return exitMetadata
+	}
+	if r.failures(statusNew, strict) > 0 {
+		return exitNew
+	}
+	return exitOK
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"testing"
+)
+
+func Test_verifyResult_exitCode(t *testing.T) {
+	tests := []struct {
+		statuses []fileStatus
+		unknown  bool
+		strict   bool
+		code     int
+	}{
+		{[]fileStatus{statusOK}, false, true, exitOK},
+		{[]fileStatus{statusOK, statusNew}, false, false, exitOK},
+		{[]fileStatus{statusOK, statusNew}, false, true, exitNew},
+		{[]fileStatus{statusNew, statusMetadata}, false, true, exitMetadata},
+		{[]fileStatus{statusMetadata, statusMissing}, false, false, exitMissing},
+		{[]fileStatus{statusMissing, statusChanged}, false, false, exitChanged},
+		{[]fileStatus{statusOK}, true, false, exitChanged},
+	}
+	for _, test := range tests {
+		// GIVEN a result with files of each status
+		r := &verifyResult{}
+		for i, status := range test.statuses {
+			r.add(&fileResult{FileName: fmt.Sprintf("%d.txt", i), Status: status})
+		}
+		if test.unknown {
+			r.Unknown = append(r.Unknown, &unknownResult{Hash: "d41d8cd98f00b204e9800998ecf8427e"})
+		}
+
+		// THEN the exit code is the first kind of failure
+		if code := r.exitCode(test.strict); code != test.code {
+			t.Errorf("Expected %v with strict %v to exit %d got %d", test.statuses, test.strict, test.code, code)
+		}
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	// THEN no error is success, an exitError has its code, and anything else is an I/O error
+	if code := exitCode(nil); code != exitOK {
+		t.Errorf("Expected %d got %d", exitOK, code)
+	}
+	if code := exitCode(fmt.Errorf("Wrapped %w", &exitError{code: exitMissing, err: errors.New("missing")})); code != exitMissing {
+		t.Errorf("Expected %d got %d", exitMissing, code)
+	}
+	if code := exitCode(errors.New("disk")); code != exitIO {
+		t.Errorf("Expected %d got %d", exitIO, code)
+	}
+}
+
+func Test_hashFolder_ExitCode(t *testing.T) {
+	// GIVEN a folder where a file changed
+	dirName, _ := makeReportFolder(t)
+	_, _, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err := h.HashFolder(dirName)
+
+	// THEN it exits with changed, even though a file is missing too
+	if code := exitCode(err); code != exitChanged {
+		t.Errorf("Expected %d got %d: %v", exitChanged, code, err)
+	}
+
+	// WHEN the -unknown file doesn't exist
+	_, _, h = makeTestFolderHasher("manifest.json", "noexist")
+	err = h.HashFolder(dirName)
+
+	// THEN it is a usage error
+	if code := exitCode(err); code != exitUsage {
+		t.Errorf("Expected %d got %d: %v", exitUsage, code, err)
+	}
+}
@@ -2,15 +2,15 @@
 
 package main
 
-// filterFiles outputs only files that are not the manifest
-func filterFiles(done chan struct{}, files chan *pathFileInfo, manifestFilename string, out chan *pathFileInfo) {
+// filterFiles outputs only files that are not the manifest, or any of the other files the filter excludes
+func filterFiles(done chan struct{}, files chan *pathFileInfo, filter *fileFilter, out chan *pathFileInfo) {
 	defer close(out)
 	for file := range files {
 		select {
 		case <-done:
 			return
 		default:
-			if filterFile(file, manifestFilename) {
+			if filterFile(file, filter) {
 				out <- file
 			}
 		}
@@ -20,16 +20,19 @@ func filterFiles(done chan struct{}, files chan *pathFileInfo, manifestFilename
 }
 
 // filterFile returns true if file should be hashed
-func filterFile(file *pathFileInfo, manifestFileName string) bool {
+// ignored files were already skipped by walkFolder, and folders are only kept when they couldn't be read, or are recorded and included
+func filterFile(file *pathFileInfo, filter *fileFilter) bool {
 	if file.IsDir() {
-		return false
+		return file.err != nil || filter.dirs && filter.included(file)
 	}
-	if file.Name() == manifestFileName {
-		return false
-	}
-	if file.name == manifestFileName {
-		return false
+	for _, excludeName := range filter.excludeNames {
+		if file.Name() == excludeName {
+			return false
+		}
+		if file.name == excludeName {
+			return false
+		}
 	}
-	return true
+	return filter.included(file)
 
 }
@@ -5,6 +5,7 @@ package main
 import (
 	"os"
 	"path"
+	"sort"
 	"testing"
 )
 
@@ -17,11 +18,12 @@ func Test_Filter_filterFiles(t *testing.T) {
 	go func() {
 		defer close(fileChan)
 		files, _ := dir.Readdir(0)
+		sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
 		for f := range files {
 			fileChan <- &pathFileInfo{
-				files[f],
-				path.Join(dirName, files[f].Name()),
-				files[f].Name(),
+				FileInfo: files[f],
+				path:     path.Join(dirName, files[f].Name()),
+				name:     files[f].Name(),
 			}
 		}
 	}()
@@ -30,7 +32,11 @@ func Test_Filter_filterFiles(t *testing.T) {
 	out := make(chan *pathFileInfo)
 
 	// WHEN the files are filtered for the folder
-	go filterFiles(done, fileChan, manifestFilename, out)
+	filter, err := newFileFilter(dirName, []string{manifestFilename}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go filterFiles(done, fileChan, filter, out)
 
 	// THEN the first file should be a.txt
 	a := <-out
@@ -6,15 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
+// errStopped is returned by walkFolder when done was closed before every file was walked.
+var errStopped = errors.New("Stopped before every file was checked")
+
 type fileNameSum struct {
 	FileName string
 	Sum      manifest.Sum
+	Info     os.FileInfo
+	Cached   bool  // the Sum came from the old manifest because the file was unchanged in the cache
+	Err      error // the file couldn't be hashed, so there is no Sum
 }
 
 type folderHasher struct {
@@ -22,12 +31,35 @@ type folderHasher struct {
 	infoLog          *log.Logger
 	manifestFileName string
 	unknownFileName  string
+	algorithms       []string
+	verifyOnly       bool
+	strict           bool
+	jobs             int
+	cacheFileName    string              // where the cache is saved, or empty for next to the manifest
+	quick            bool                // use the cache to skip hashing unchanged files
+	full             bool                // hash every file, and save a fresh cache
+	format           manifest.Format     // the format the manifest is saved in, or nil for the format it was loaded in
+	pathPolicy       manifest.PathPolicy // how file names are matched to the manifest
+	excludes         []string            // patterns of files that aren't hashed, like lines in a .verifyignore
+	includes         []string            // patterns of the only files that are hashed, or empty for every file
+	symlinks         symlinkPolicy       // whether links are followed, skipped or recorded
+	meta             []string            // metadata attributes saved in the manifest, ex. manifest.Mode
+	checkMeta        []string            // metadata attributes that must match the manifest, they are saved too
+	dirs             bool                // folders are recorded in the manifest, so an empty or removed folder is noticed
+	acceptMoves      bool                // files that were moved or renamed without changing don't fail
+	update           bool                // the manifest is saved even when files changed, moved or are missing, and every change is logged
+	accepts          []string            // patterns of the files that may change when updating, or empty for every file
+	report           reportFormat        // the format of the report, if any
+	reportFileName   string              // where the report is saved, or empty to write it to reportOutput
+	reportOutput     io.Writer
 }
 
 type pathFileInfo struct {
 	os.FileInfo
-	path string
-	name string
+	path   string       // the path to open the file
+	name   string       // the file name relative to the folder, with forward slashes like in the manifest
+	cached manifest.Sum // the previous sum if the file is unchanged in the cache
+	err    error        // why the file couldn't be walked, ex. a link that can't be followed, it's reported instead of hashed
 }
 
 // NewFolderHasher returns a folderHasher that can be used to verify the contents of every file in the folder.
@@ -45,31 +77,66 @@ func NewFolderHasher(manifestFileName, unknownFileName string, infoLog, errorLog
 }
 
 // HashFolder goes through the directory, calculate all the hashes, and save them to a manifest.
+// In verify only mode the manifest must already exist, and it is never saved.
 func (h *folderHasher) HashFolder(dirName string) error {
-	oldManifest, unknownHashes, err := h.loadPreviousHashes(dirName)
+	oldManifest, oldFormat, unknownHashes, err := h.loadPreviousHashes(dirName)
 	if err != nil {
 		return err
 	}
+	cache := h.loadCache(dirName)
+	filter, err := newFileFilter(dirName, h.excludeNames(dirName), h.excludes, h.includes)
+	if err != nil {
+		return &exitError{code: exitUsage, err: err}
+	}
+	filter.dirs = h.dirs
+	accepts, err := parseIgnoreFlags(h.accepts)
+	if err != nil {
+		return &exitError{code: exitUsage, err: err}
+	}
 
 	done := make(chan struct{})
 	files := make(chan *pathFileInfo)
 	filteredFiles := make(chan *pathFileInfo)
 	fileNameSums := make(chan *fileNameSum)
-	go walkFolder(dirName, done, files)
-	go filterFiles(done, files, h.manifestFileName, filteredFiles)
+	walkErr := make(chan error, 1)
 	go func() {
-		if err := streamHashes(done, filteredFiles, fileNameSums); err != nil {
-			h.errorLog.Println(err)
-		}
+		walkErr <- walkFolder(dirName, h.symlinks, filter, done, files)
 	}()
-	newManifest, verifyFail := h.verifyFiles(done, fileNameSums, oldManifest, unknownHashes)
-	verifyUnknownFail := h.verifyUnknownHashes(unknownHashes)
-	if verifyFail || verifyUnknownFail {
-		return errors.New("Some hashes failed, manifest not updated.")
+	go filterFiles(done, files, filter, filteredFiles)
+	hashFiles := filteredFiles
+	if cache != nil {
+		hashFiles = make(chan *pathFileInfo)
+		go useCache(done, filteredFiles, cache, oldManifest, oldManifest.Index(h.pathPolicy), h.algorithms, hashFiles)
+	}
+	go streamHashes(done, hashFiles, h.algorithms, h.jobs, fileNameSums)
+	newManifest, newCache, result := h.verifyFiles(done, fileNameSums, oldManifest, unknownHashes)
+	h.verifyUnknownHashes(unknownHashes, result)
+	if err := <-walkErr; err != nil {
+		return fmt.Errorf("Error reading folder %v: %v", dirName, err)
+	}
+	h.verifyNewFiles(oldManifest, result)
+	h.verifyMissingFiles(oldManifest, result)
+	if h.update {
+		h.acceptUpdates(result, accepts)
+	}
+	h.infoLog.Println(result.summary())
+	if err := h.saveCache(dirName, newCache); err != nil {
+		return err
+	}
+	if err := h.writeReport(dirName, result); err != nil {
+		return err
+	}
+	if result.failed(h.strict) {
+		return &exitError{code: result.exitCode(h.strict), err: errors.New("Some hashes failed, manifest not updated.")}
+	}
+
+	if h.verifyOnly {
+		h.infoLog.Printf("Verified %d files with %v\n", len(*newManifest), path.Join(dirName, h.manifestFileName))
+		return nil
 	}
 
 	if len(h.manifestFileName) > 0 {
-		if err := newManifest.Save(dirName, h.manifestFileName); err != nil {
+		if err := newManifest.SaveFormat(dirName, h.manifestFileName, h.saveFormat(oldFormat)); err != nil {
 			return fmt.Errorf("Error saving manifest %v", err)
 		}
 		h.infoLog.Printf("Saved manifest to %v\n", path.Join(dirName, h.manifestFileName))
@@ -77,73 +144,304 @@ func (h *folderHasher) HashFolder(dirName string) error {
 	return nil
 }
 
+// hashFolderFiles hashes every file in the folder that isn't filtered, for commands that don't use a manifest
+// a file that couldn't be read has its Err set, only an error reading the folder is returned
+func (h *folderHasher) hashFolderFiles(dirName string) ([]*fileNameSum, error) {
+	filter, err := newFileFilter(dirName, h.excludeNames(dirName), h.excludes, h.includes)
+	if err != nil {
+		return nil, &exitError{code: exitUsage, err: err}
+	}
+	done := make(chan struct{})
+	files := make(chan *pathFileInfo)
+	filteredFiles := make(chan *pathFileInfo)
+	fileNameSums := make(chan *fileNameSum)
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- walkFolder(dirName, h.symlinks, filter, done, files)
+	}()
+	go filterFiles(done, files, filter, filteredFiles)
+	go streamHashes(done, filteredFiles, h.algorithms, h.jobs, fileNameSums)
+	var sums []*fileNameSum
+	for f := range fileNameSums {
+		sums = append(sums, f)
+	}
+	if err := <-walkErr; err != nil {
+		return nil, fmt.Errorf("Error reading folder %v: %v", dirName, err)
+	}
+	return sums, nil
+}
+
+// unreadableFileInfo is the FileInfo of a file that couldn't be read with os.Lstat, so it can still be reported
+type unreadableFileInfo struct {
+	name string
+}
+
+func (i unreadableFileInfo) Name() string       { return i.name }
+func (i unreadableFileInfo) Size() int64        { return 0 }
+func (i unreadableFileInfo) Mode() os.FileMode  { return 0 }
+func (i unreadableFileInfo) ModTime() time.Time { return time.Time{} }
+func (i unreadableFileInfo) IsDir() bool        { return false }
+func (i unreadableFileInfo) Sys() interface{}   { return nil }
+
 // walkFolder will walk through all the files in dirName and source them into the files channel
-func walkFolder(dirName string, done chan struct{}, files chan *pathFileInfo) (err error) {
+// `symlinks` is whether links are followed, skipped, or sent as links so their target is recorded
+// `filter` skips ignored files, and ignored folders aren't walked at all, it can be nil to send every file
+func walkFolder(dirName string, symlinks symlinkPolicy, filter *fileFilter, done chan struct{}, files chan *pathFileInfo) (err error) {
 	defer close(files)
-	err = filepath.Walk(dirName, func(path string, info os.FileInfo, err error) error {
+	return walkLinkedFolder(dirName, dirName, symlinks, filter, &linkedFolders{}, done, files)
+}
+
+// walkLinkedFolder walks linkDir, which is dirName or a folder inside it that a followed link points to
+// `linked` are the folders that links were already followed to in this walk
+func walkLinkedFolder(dirName, linkDir string, symlinks symlinkPolicy, filter *fileFilter, linked *linkedFolders, done chan struct{}, files chan *pathFileInfo) error {
+	walkDir := linkDir
+	if linkDir != dirName {
+		// with a trailing separator the link is walked as the folder it points to
+		walkDir += string(filepath.Separator)
+	}
+	return filepath.Walk(walkDir, func(path string, info os.FileInfo, walkErr error) error {
+		if path == walkDir {
+			if walkErr != nil && linkDir == dirName {
+				// nothing can be checked without the root folder
+				close(done)
+				return walkErr
+			}
+			if walkErr == nil {
+				return nil
+			}
+			// the folder a followed link points to couldn't be read, so the link is reported
+		}
+		if info == nil {
+			info = unreadableFileInfo{name: filepath.Base(path)}
+		}
+		name, err := filepath.Rel(dirName, path)
 		if err != nil {
 			close(done)
 			return err
 		}
-		if path == dirName {
-			return nil
-		}
-		files <- &pathFileInfo{
+		// a folder or file that couldn't be read is reported with its error, and the rest of the folder is still walked
+		file := &pathFileInfo{
 			FileInfo: info,
 			path:     path,
-			name:     path[len(dirName)+1:],
+			name:     filepath.ToSlash(name),
+			err:      walkErr,
+		}
+		followDir := false
+		if walkErr == nil && isSymlink(info) {
+			switch symlinks {
+			case symlinksSkip:
+				return nil
+			case symlinksFollow:
+				if target, err := os.Stat(path); err != nil {
+					// the link is reported as a file that couldn't be read, the rest of the folder is still walked
+					file.err = fmt.Errorf("Couldn't follow link: %v", err)
+				} else if target.IsDir() && isLinkLoop(path, target) {
+					return nil
+				} else {
+					file.FileInfo = target
+					followDir = target.IsDir()
+				}
+			}
+		}
+		if filter != nil && filter.ignored(file) {
+			if file.IsDir() && !followDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if followDir && !linked.visit(file.FileInfo) {
+			// another link already walked the folder this one points to
+			return nil
+		}
+		select {
+		case <-done:
+			return errStopped
+		case files <- file:
+		}
+		if followDir {
+			return walkLinkedFolder(dirName, path, symlinks, filter, linked, done, files)
 		}
 		return nil
 	})
-	return
 }
 
-// any unknown hashes left are failures
-func (h *folderHasher) verifyUnknownHashes(unknownHashes *manifest.UnknownHashes) bool {
-	verifyFail := false
-	if unknownHashes != nil {
-		for k, v := range *unknownHashes {
-			verifyFail = true
-			h.errorLog.Printf("Hash %v was in %v line %d, but not found in dir: %v", k, h.unknownFileName, v.LineNumber, v.Line)
+// any unknown hashes left are failures, they are added to the result in the order of their lines
+func (h *folderHasher) verifyUnknownHashes(unknownHashes *manifest.UnknownHashes, result *verifyResult) {
+	if unknownHashes == nil {
+		return
+	}
+	for k, v := range *unknownHashes {
+		result.Unknown = append(result.Unknown, &unknownResult{Hash: k, Location: v})
+	}
+	sort.Slice(result.Unknown, func(i, j int) bool {
+		a, b := result.Unknown[i], result.Unknown[j]
+		if a.Location.LineNumber != b.Location.LineNumber {
+			return a.Location.LineNumber < b.Location.LineNumber
+		}
+		return a.Hash < b.Hash
+	})
+	for _, u := range result.Unknown {
+		h.errorLog.Printf("Hash %v was in %v line %d, but not found in dir: %v", u.Hash, h.unknownFileName, u.Location.LineNumber, u.Location.Line)
+	}
+}
+
+// any files in the oldManifest that weren't found in the dir are added to the result as missing
+// files inside a folder that couldn't be read aren't missing, the folder is already an error
+func (h *folderHasher) verifyMissingFiles(oldManifest *manifest.Manifest, result *verifyResult) {
+	found := result.manifestNames()
+	unread := result.errorNames()
+	fileNames := make([]string, 0, len(*oldManifest))
+	for fileName, sum := range *oldManifest {
+		if sum.IsDir() && !h.dirs {
+			// folders are only checked when they are recorded
+			continue
+		}
+		if !found[fileName] && !inErrorFolder(fileName, unread) {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	sort.Strings(fileNames)
+	for _, fileName := range fileNames {
+		result.add(&fileResult{
+			FileName: fileName,
+			Status:   statusMissing,
+			Expected: (*oldManifest)[fileName],
+		})
+		if h.update {
+			continue
 		}
+		if (*oldManifest)[fileName].IsDir() {
+			h.errorLog.Printf("Missing folder %v was in %v, but not found in dir", fileName, h.manifestFileName)
+			continue
+		}
+		h.errorLog.Printf("Missing %v was in %v, but not found in dir: %v", fileName, h.manifestFileName, (*oldManifest)[fileName])
 	}
-	return verifyFail
+}
+
+// inErrorFolder returns true if one of the folders above the file is in `unread`
+func inErrorFolder(fileName string, unread map[string]bool) bool {
+	for dirName := path.Dir(fileName); dirName != "." && dirName != "/"; dirName = path.Dir(dirName) {
+		if unread[dirName] {
+			return true
+		}
+	}
+	return false
+}
+
+// hashJob is a file being hashed by one of the streamHashes workers
+type hashJob struct {
+	file  *pathFileInfo
+	fs    *fileNameSum
+	err   error
+	ready chan struct{} // closed when the hash is calculated
 }
 
 // go through all the files in files stream, calculate the hash, and then send the result over the result stream
-func streamHashes(done chan struct{}, files chan *pathFileInfo, result chan *fileNameSum) error {
+// `algorithms` are the names of the registered hash algorithms to calculate, or nil for the defaults
+// `jobs` is how many files are hashed at the same time, results are still sent in the same order as files
+// a file that can't be read is sent with its Err, and the rest of the files are still hashed
+func streamHashes(done chan struct{}, files chan *pathFileInfo, algorithms []string, jobs int, result chan *fileNameSum) {
 	defer close(result)
+	if jobs < 1 {
+		jobs = 1
+	}
+	pending := make(chan *hashJob, jobs)
+	work := make(chan *hashJob)
+	go queueHashJobs(done, files, pending, work)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			for job := range work {
+				if job.file.err != nil {
+					job.err = job.file.err
+				} else if job.file.cached != nil {
+					job.fs.Sum = job.file.cached.Hashes()
+					job.fs.Cached = true
+				} else if isSymlink(job.file.FileInfo) {
+					job.err = job.fs.Sum.ReadLink(job.file.path)
+				} else if job.file.IsDir() {
+					job.fs.Sum = manifest.Sum{manifest.Type: manifest.TypeDir}
+				} else {
+					job.err = job.fs.Sum.Calculate(job.file.path, algorithms...)
+				}
+				close(job.ready)
+			}
+		}()
+	}
+	for job := range pending {
+		select {
+		case <-done:
+			return
+		case <-job.ready:
+		}
+		job.fs.Err = job.err
+		select {
+		case <-done:
+			return
+		case result <- job.fs:
+		}
+	}
+}
+
+// queueHashJobs sends every file to the workers, and to pending in the order they arrived
+func queueHashJobs(done chan struct{}, files chan *pathFileInfo, pending, work chan *hashJob) {
+	defer close(pending)
+	defer close(work)
 	for file := range files {
-		fs := &fileNameSum{
-			FileName: file.name,
+		job := &hashJob{
+			file:  file,
+			fs:    &fileNameSum{FileName: file.name, Info: file.FileInfo},
+			ready: make(chan struct{}),
 		}
-		err := fs.Sum.Calculate(file.path)
-		if err != nil {
-			close(done)
-			return err
+		select {
+		case <-done:
+			return
+		default:
 		}
 		select {
 		case <-done:
-			return nil
-		case result <- fs:
+			return
+		case pending <- job:
+		}
+		select {
+		case <-done:
+			return
+		case work <- job:
 		}
 	}
-	return nil
 }
 
 // go though all the hashes in the fileNameSums stream, save them in the newManifest, and remove them from unknownHashes
-func (h *folderHasher) verifyFiles(done chan struct{}, fileNameSums chan *fileNameSum, oldManifest *manifest.Manifest, unknownHashes *manifest.UnknownHashes) (newManifest *manifest.Manifest, verifyFail bool) {
+// every file is classified as ok, changed, metadata or new compared to the oldManifest
+// files whose contents are ok or new are put in the newCache, so they can be skipped next time if they don't change
+func (h *folderHasher) verifyFiles(done chan struct{}, fileNameSums chan *fileNameSum, oldManifest *manifest.Manifest, unknownHashes *manifest.UnknownHashes) (newManifest *manifest.Manifest, newCache *manifest.Cache, result *verifyResult) {
 	newManifest = &manifest.Manifest{}
+	newCache = &manifest.Cache{}
+	result = &verifyResult{}
+	index := oldManifest.Index(h.pathPolicy)
+	seen := map[string]string{}
 	for f := range fileNameSums {
+		key := h.pathPolicy.Key(f.FileName)
+		if other, ok := seen[key]; ok {
+			h.infoLog.Printf("Warning: %v and %v are the same file name with -paths %v\n", other, f.FileName, h.pathPolicy)
+		} else {
+			seen[key] = f.FileName
+		}
+		if f.Err != nil {
+			result.add(h.fileError(f, oldManifest, index))
+			continue
+		}
+		f.Sum.SetAttributes(f.Info, h.meta...)
+		f.Sum.SetAttributes(f.Info, h.checkMeta...)
 		(*newManifest)[f.FileName] = f.Sum
-		if err := oldManifest.Verify(f.FileName, f.Sum); err != nil {
-			verifyFail = true
-			h.errorLog.Printf("Error %v: %v\n", f.FileName, err)
+		fr := h.verifyFile(f, oldManifest, index)
+		result.add(fr)
+		if fr.Status == statusOK || fr.Status == statusMetadata || fr.Status == statusNew {
+			(*newCache)[f.FileName] = manifest.NewFileStat(f.Info)
 		}
 		if unknownHashes != nil {
 			unknownHashes.RemoveSum(f.Sum)
 		}
-		h.infoLog.Printf("%v\tmd5:%v\tsha1:%v\n", f.FileName, f.Sum.MD5, f.Sum.SHA1)
+		h.infoLog.Printf("%v\t%v\n", f.FileName, f.Sum)
 		select {
 		case <-done:
 			return
@@ -153,12 +451,71 @@ func (h *folderHasher) verifyFiles(done chan struct{}, fileNameSums chan *fileNa
 	return
 }
 
-// load the oldManifest and/or an unknownHahses file
-func (h *folderHasher) loadPreviousHashes(dirName string) (oldManifest *manifest.Manifest, unknownHashes *manifest.UnknownHashes, err error) {
+// fileError is the result for a file that couldn't be hashed, it's found in the index so it isn't reported as missing too
+func (h *folderHasher) fileError(f *fileNameSum, oldManifest *manifest.Manifest, index *manifest.Index) *fileResult {
+	fr := &fileResult{
+		FileName: f.FileName,
+		Status:   statusError,
+		Err:      f.Err,
+	}
+	if manifestName, ok := index.Find(f.FileName); ok {
+		fr.manifestName = manifestName
+		fr.Expected = (*oldManifest)[manifestName]
+	}
+	h.errorLog.Printf("Error reading %v: %v\n", f.FileName, f.Err)
+	return fr
+}
+
+// compare one calculated sum to the oldManifest and log anything that isn't ok
+// the file is found in the oldManifest with the index, so the name can be different depending on the path policy
+func (h *folderHasher) verifyFile(f *fileNameSum, oldManifest *manifest.Manifest, index *manifest.Index) *fileResult {
+	fr := &fileResult{
+		FileName: f.FileName,
+		Status:   statusOK,
+		Actual:   f.Sum,
+		Cached:   f.Cached,
+	}
+	manifestName, ok := index.Find(f.FileName)
+	if !ok {
+		// new files are logged after every file is checked, when it's known if they were moved
+		fr.Status = statusNew
+		return fr
+	}
+	fr.manifestName = manifestName
+	expected := (*oldManifest)[manifestName]
+	fr.Expected = expected
+	if err := expected.Verify(f.Sum); err != nil {
+		fr.Status = statusChanged
+		fr.Err = err
+	} else if err := expected.VerifyAttributes(f.Sum, h.checkMeta...); err != nil {
+		fr.Status = statusMetadata
+		fr.Err = err
+	}
+	if h.update {
+		// changes are logged after every file is checked, when it's known if they are accepted
+		return fr
+	}
+	switch fr.Status {
+	case statusChanged:
+		h.errorLog.Printf("Error %v: %v\n", f.FileName, fr.Err)
+	case statusMetadata:
+		h.errorLog.Printf("Metadata %v: %v\n", f.FileName, fr.Err)
+	}
+	return fr
+}
+
+// load the oldManifest and/or an unknownHahses file, `oldFormat` is the format of the oldManifest, or nil if it wasn't loaded
+func (h *folderHasher) loadPreviousHashes(dirName string) (oldManifest *manifest.Manifest, oldFormat manifest.Format, unknownHashes *manifest.UnknownHashes, err error) {
 	oldManifest = &manifest.Manifest{}
 
+	if h.verifyOnly && len(h.manifestFileName) == 0 {
+		return nil, nil, nil, &exitError{code: exitUsage, err: errors.New("A manifest file is required to verify")}
+	}
 	if len(h.manifestFileName) > 0 {
-		if err := oldManifest.Load(dirName, h.manifestFileName); err != nil {
+		if oldFormat, err = oldManifest.LoadFormat(dirName, h.manifestFileName); err != nil {
+			if h.verifyOnly {
+				return nil, nil, nil, err
+			}
 			h.infoLog.Println("Warning:", err)
 			h.infoLog.Println("Continuing.")
 		}
@@ -166,8 +523,19 @@ func (h *folderHasher) loadPreviousHashes(dirName string) (oldManifest *manifest
 	if h.unknownFileName != "" {
 		unknownHashes, err = manifest.LoadUnknownHashes(h.unknownFileName)
 		if err != nil {
-			return nil, nil, fmt.Errorf("Unable load \"unknown\" hash file: %v", err)
+			return nil, nil, nil, &exitError{code: exitUsage, err: fmt.Errorf("Unable load \"unknown\" hash file: %v", err)}
 		}
 	}
-	return
+	return oldManifest, oldFormat, unknownHashes, nil
+}
+
+// saveFormat is -format if it was chosen, or else the format of the old manifest, or JSON for a new manifest
+func (h *folderHasher) saveFormat(oldFormat manifest.Format) manifest.Format {
+	if h.format != nil {
+		return h.format
+	}
+	if oldFormat != nil {
+		return oldFormat
+	}
+	return manifest.JSONFormat{}
 }
@@ -5,9 +5,13 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -18,25 +22,18 @@ func Test_streamHashes(t *testing.T) {
 	files := make(chan *pathFileInfo)
 	go func() {
 		fi, _ := os.Stat("test_data/a.txt")
-		files <- &pathFileInfo{fi, path.Join(dirName, fi.Name()), fi.Name()}
+		files <- &pathFileInfo{FileInfo: fi, path: path.Join(dirName, fi.Name()), name: fi.Name()}
 		close(files)
 	}()
 	done := make(chan struct{})
 
 	// WHEN the files are streamed
 	results := make(chan *fileNameSum)
-	go func() {
-		err := streamHashes(done, files, results)
-
-		// THEN there were no errors
-		if err != nil {
-			t.Fatal(err)
-		}
-	}()
+	go streamHashes(done, files, nil, 1, results)
 
 	// THEN the hashes are generated for a
 	a := <-results
-	if a.Sum.MD5 != "0cc175b9c0f1b6a831c399e269772661" {
+	if a.Sum[manifest.MD5] != "0cc175b9c0f1b6a831c399e269772661" {
 		t.Fatalf("a.txt hash unexpected %v", a)
 	}
 
@@ -55,20 +52,15 @@ func Test_streamHashes_FileError(t *testing.T) {
 	go func() {
 		close(done)
 		fi, _ := os.Stat("test_data/a.txt")
-		files <- &pathFileInfo{fi, path.Join(dirName, fi.Name()), fi.Name()}
+		files <- &pathFileInfo{FileInfo: fi, path: path.Join(dirName, fi.Name()), name: fi.Name()}
 		fi, _ = os.Stat("test_data/b.txt")
-		files <- &pathFileInfo{fi, path.Join(dirName, fi.Name()), fi.Name()}
+		files <- &pathFileInfo{FileInfo: fi, path: path.Join(dirName, fi.Name()), name: fi.Name()}
 		close(files)
 	}()
 
 	// WHEN the files are streamed
 	results := make(chan *fileNameSum)
-	err := streamHashes(done, files, results)
-
-	// THEN there shouldn't be any errors
-	if err != nil {
-		t.Errorf("Should be no return error if just done: %v", err)
-	}
+	go streamHashes(done, files, nil, 1, results)
 
 	// THEN there shouldn't be any results
 	for f := range results {
@@ -83,7 +75,7 @@ func Test_loadPreviousHashes_MissingManifest(t *testing.T) {
 	infoBuffer, errorBuffer, h := makeTestFolderHasher(manifestName, "")
 
 	// WHEN the manifest is loaded
-	_, _, err := h.loadPreviousHashes(dirName)
+	_, _, _, err := h.loadPreviousHashes(dirName)
 
 	// THEN there should be not be an error that the manifest didn't exist
 	if err != nil {
@@ -111,7 +103,7 @@ func Test_loadPreviousHashes_FileLoadError(t *testing.T) {
 	_, _, h := makeTestFolderHasher(manifestFilename, unknownFilename)
 
 	// WHEN the folder is hashed
-	_, _, err := h.loadPreviousHashes(dirName)
+	_, _, _, err := h.loadPreviousHashes(dirName)
 
 	// THEN there should be a failure that the file didn't exist
 	if err == nil {
@@ -250,3 +242,440 @@ func makeTestFolderHasher(manifestFileName, unknownFileName string) (infoBuffer,
 	hasher = NewFolderHasher(manifestFileName, unknownFileName, infoLog, errorLog)
 	return
 }
+
+// writeTestFiles writes each file name, with forward slashes, in dirName with its contents, making any sub folders
+func writeTestFiles(t *testing.T, dirName string, files map[string]string) {
+	for name, contents := range files {
+		fileName := filepath.Join(dirName, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fileName, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_hashFolder_VerifyOnly(t *testing.T) {
+	// GIVEN a folder with a valid manifest in verify only mode
+	dirName := "test_data"
+	manifestFile := "manifest.json"
+	infoBuffer, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+	h.verifyOnly = true
+	before, err := os.Stat(path.Join(dirName, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN the folder is hashed
+	err = h.HashFolder(dirName)
+
+	// THEN it should verify without an error
+	if err != nil {
+		t.Errorf("Should have verified: %v %v", err, errorBuffer)
+	}
+
+	// THEN the manifest should not have been saved
+	after, err := os.Stat(path.Join(dirName, manifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Error("The manifest should not have been written in verify only mode")
+	}
+	if strings.Contains(infoBuffer.String(), "Saved") {
+		t.Errorf("The manifest should not have been saved: %v", infoBuffer)
+	}
+}
+
+func Test_hashFolder_VerifyOnly_MissingManifest(t *testing.T) {
+	// GIVEN verify only mode and a manifest that doesn't exist
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a"})
+	manifestFile := "noexist"
+	infoBuffer, _, h := makeTestFolderHasher(manifestFile, "")
+	h.verifyOnly = true
+
+	// WHEN the folder is hashed
+	err := h.HashFolder(dirName)
+
+	// THEN it should fail instead of continuing
+	if err == nil {
+		t.Error("Should have failed without a manifest")
+	}
+	if strings.Contains(infoBuffer.String(), "Continuing") {
+		t.Errorf("Should not continue without a manifest: %v", infoBuffer)
+	}
+	if _, err := os.Stat(path.Join(dirName, manifestFile)); err == nil {
+		t.Error("The manifest should not have been created")
+	}
+}
+
+func Test_hashFolder_MissingFile(t *testing.T) {
+	// GIVEN a manifest with a file that isn't in the folder
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "b.txt": "b"})
+	manifestFile := "missing_manifest.json"
+	m := manifest.Manifest{
+		"a.txt":    manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"gone.txt": manifest.Sum{manifest.MD5: "48e2a9e44a8d96a6b07eab35a86aa556"},
+	}
+	if err := m.Save(dirName, manifestFile); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+
+	// WHEN the folder is hashed
+	err := h.HashFolder(dirName)
+
+	// THEN it should fail
+	if err == nil {
+		t.Error("Should have failed because gone.txt is missing")
+	}
+
+	// THEN the missing file and its recorded hash should be in the error log
+	es := errorBuffer.String()
+	if !strings.Contains(es, "gone.txt") || !strings.Contains(es, "48e2a9e44a8d96a6b07eab35a86aa556") {
+		t.Errorf("Error log should have the missing file and hash: %v", es)
+	}
+
+	// THEN the manifest shouldn't have been updated
+	loaded := manifest.Manifest{}
+	if err := loaded.Load(dirName, manifestFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded["gone.txt"]; !ok {
+		t.Error("The manifest should not have been updated")
+	}
+}
+
+func Test_hashFolder_Strict_NewFile(t *testing.T) {
+	// GIVEN a manifest that only has a.txt
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "b.txt": "b"})
+	manifestFile := "strict_manifest.json"
+	m := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+	}
+	if err := m.Save(dirName, manifestFile); err != nil {
+		t.Fatal(err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+	h.strict = true
+
+	// WHEN the folder is hashed in strict mode
+	err := h.HashFolder(dirName)
+
+	// THEN it should fail because of the new files
+	if err == nil {
+		t.Error("Should have failed because b.txt is new")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "New b.txt") {
+		t.Errorf("Error log should have the new file: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "1 ok") {
+		t.Errorf("Info log should have a summary: %v", is)
+	}
+
+	// WHEN the folder is hashed without strict mode
+	infoBuffer, errorBuffer, h = makeTestFolderHasher(manifestFile, "")
+	err = h.HashFolder(dirName)
+
+	// THEN the new files are accepted
+	if err != nil {
+		t.Errorf("New files should be accepted: %v %v", err, errorBuffer)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "New b.txt") {
+		t.Errorf("Info log should have the new file: %v", is)
+	}
+}
+
+func Test_streamHashes_Jobs(t *testing.T) {
+	// GIVEN every file in the test folder
+	dirName := "test_data"
+	hashAll := func(jobs int) (names []string) {
+		done := make(chan struct{})
+		files := make(chan *pathFileInfo)
+		filtered := make(chan *pathFileInfo)
+		results := make(chan *fileNameSum)
+		go walkFolder(dirName, symlinksFollow, nil, done, files)
+		filter, _ := newFileFilter(dirName, []string{"manifest.json"}, nil, nil)
+		go filterFiles(done, files, filter, filtered)
+		go streamHashes(done, filtered, []string{manifest.MD5}, jobs, results)
+		for r := range results {
+			names = append(names, r.FileName)
+		}
+		return
+	}
+
+	// WHEN the files are hashed one at a time and then with several workers
+	serial := hashAll(1)
+	parallel := hashAll(4)
+
+	// THEN the results should be in the same order
+	if len(serial) == 0 || len(serial) != len(parallel) {
+		t.Fatalf("Expected the same files %v %v", serial, parallel)
+	}
+	for i := range serial {
+		if serial[i] != parallel[i] {
+			t.Errorf("Result %d was %v with one job but %v with four", i, serial[i], parallel[i])
+		}
+	}
+}
+
+func Test_hashFolder_VerifyOnly_GNU(t *testing.T) {
+	// GIVEN a sha256sum manifest
+	dirName := "test_data"
+	manifestFile := "other_manifests/sha256sums.txt"
+	_, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err := h.HashFolder(dirName)
+
+	// THEN each named file should have been verified
+	if err != nil {
+		t.Errorf("Should have verified: %v %v", err, errorBuffer)
+	}
+}
+
+func Test_hashFolder_VerifyOnly_BSD(t *testing.T) {
+	// GIVEN a tagged manifest
+	dirName := "test_data"
+	manifestFile := "other_manifests/tagged.txt"
+	_, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err := h.HashFolder(dirName)
+
+	// THEN each tagged hash should have been verified
+	if err != nil {
+		t.Errorf("Should have verified: %v %v", err, errorBuffer)
+	}
+}
+
+func Test_hashFolder_LegacyWindowsManifest(t *testing.T) {
+	// GIVEN a manifest made on Windows with backslashes in the file names
+	dirName := "test_data"
+	manifestFile := "other_manifests/legacy_windows.json"
+	_, errorBuffer, h := makeTestFolderHasher(manifestFile, "")
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err := h.HashFolder(dirName)
+
+	// THEN the files in sub folders should be found
+	if err != nil {
+		t.Errorf("Should have verified: %v %v", err, errorBuffer)
+	}
+}
+
+func Test_hashFolder_BackslashFileName(t *testing.T) {
+	// GIVEN a file with a backslash in its name, which is allowed on Unix
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "a\\b.txt"), []byte("a"), 0644); err != nil {
+		t.Skip("Backslashes aren't allowed in file names:", err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// WHEN the manifest it just saved is verified
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	err := h.HashFolder(dirName)
+
+	// THEN the file is found with the same name
+	if err != nil {
+		t.Errorf("Should have verified: %v %v", err, errorBuffer)
+	}
+}
+
+func Test_hashFolder_SaveGNU(t *testing.T) {
+	// GIVEN a folder and the sha256sum format
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("SHA256SUMS", "")
+	h.format = manifest.GNUFormat{Algorithm: manifest.SHA256}
+
+	// WHEN the folder is hashed
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the manifest should be saved like sha256sum
+	data, err := ioutil.ReadFile(path.Join(dirName, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.txt\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q got %q", expected, string(data))
+	}
+}
+
+func Test_hashFolder_KeepFormat(t *testing.T) {
+	tests := []struct {
+		manifestFile string
+		contents     string
+	}{
+		{"SHA256SUMS", "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.txt\n"},
+		{"CHECKSUMS", "SHA256 (a.txt) = ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb\n"},
+	}
+	for _, test := range tests {
+		// GIVEN a folder with a GNU or BSD manifest, and a new file
+		dirName := t.TempDir()
+		if err := ioutil.WriteFile(path.Join(dirName, "a.txt"), []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dirName, "b.txt"), []byte("b"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dirName, test.manifestFile), []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, errorBuffer, h := makeTestFolderHasher(test.manifestFile, "")
+
+		// WHEN the folder is hashed without choosing a format
+		if err := h.HashFolder(dirName); err != nil {
+			t.Fatal(err, errorBuffer)
+		}
+
+		// THEN the manifest is saved in the same format with the same algorithm
+		data, err := ioutil.ReadFile(path.Join(dirName, test.manifestFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := test.contents + strings.Replace(strings.Replace(test.contents, "a.txt", "b.txt", 1), "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d", 1)
+		if string(data) != expected {
+			t.Errorf("Expected %q got %q", expected, string(data))
+		}
+	}
+}
+
+func Test_hashFolder_EmptyManifest(t *testing.T) {
+	// GIVEN a folder with an empty manifest
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "manifest.json": ""})
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN the folder is hashed without choosing a format
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the manifest is saved as JSON
+	m := manifest.Manifest{}
+	format, err := m.LoadFormat(dirName, "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := format.(manifest.JSONFormat); !ok {
+		t.Errorf("Expected a JSON manifest got %T", format)
+	}
+	if _, ok := m["a.txt"]; !ok {
+		t.Errorf("a.txt should be in the manifest %v", m)
+	}
+}
+
+func Test_streamHashes_KeepGoing(t *testing.T) {
+	// GIVEN a file that doesn't exist, and then a file that does
+	dirName := "test_data"
+	files := make(chan *pathFileInfo)
+	go func() {
+		fi, _ := os.Stat("test_data/a.txt")
+		files <- &pathFileInfo{FileInfo: fi, path: path.Join(dirName, "noexist.txt"), name: "noexist.txt"}
+		files <- &pathFileInfo{FileInfo: fi, path: path.Join(dirName, fi.Name()), name: fi.Name()}
+		close(files)
+	}()
+	done := make(chan struct{})
+
+	// WHEN the files are streamed
+	results := make(chan *fileNameSum)
+	go streamHashes(done, files, []string{manifest.MD5}, 2, results)
+
+	// THEN the missing file has an error
+	missing := <-results
+	if missing == nil || missing.FileName != "noexist.txt" || missing.Err == nil {
+		t.Fatalf("Expected an error for noexist.txt got %v", missing)
+	}
+
+	// THEN the next file is still hashed
+	a := <-results
+	if a == nil || a.Err != nil || a.Sum[manifest.MD5] != "0cc175b9c0f1b6a831c399e269772661" {
+		t.Fatalf("a.txt should have been hashed %v", a)
+	}
+}
+
+func Test_hashFolder_ReadError(t *testing.T) {
+	// GIVEN a folder with a file that can't be opened, and a file that can
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", path.Join(dirName, "socket"))
+	if err != nil {
+		t.Skip("Unix sockets aren't supported:", err)
+	}
+	defer listener.Close()
+	m := manifest.Manifest{
+		"a.txt":  manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"socket": manifest.Sum{manifest.MD5: "d41d8cd98f00b204e9800998ecf8427e"},
+	}
+	if err := m.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err = h.HashFolder(dirName)
+
+	// THEN the error is counted as a failure with its path, and the other file was still checked
+	if exitCode(err) != exitIO {
+		t.Errorf("Expected an I/O error exit code: %v", err)
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Error reading socket") || strings.Contains(es, "Missing") {
+		t.Errorf("Expected only an error reading the socket: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "1 ok") || !strings.Contains(is, "1 error") {
+		t.Errorf("Expected a.txt to be ok and the socket to be an error: %v", is)
+	}
+}
+
+func Test_hashFolder_UnreadableFolder(t *testing.T) {
+	// GIVEN a folder with a manifest, and then a sub folder that can't be read
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "sub/b.txt": "a"})
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+	if err := os.Chmod(path.Join(dirName, "sub"), 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(path.Join(dirName, "sub"), 0755) })
+	if _, err := ioutil.ReadDir(path.Join(dirName, "sub")); err == nil {
+		t.Skip("The folder can still be read, permissions aren't enforced for this user")
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// WHEN the folder is verified
+	err := h.HashFolder(dirName)
+
+	// THEN the sub folder is an error, the files inside it aren't missing, and the other file was still checked
+	if exitCode(err) != exitIO {
+		t.Errorf("Expected an I/O error exit code: %v", err)
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Error reading sub") || strings.Contains(es, "Missing") {
+		t.Errorf("Expected only an error reading the sub folder: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "1 ok") || !strings.Contains(is, "1 error") {
+		t.Errorf("Expected a.txt to be ok and the sub folder to be an error: %v", is)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is the file in any folder that has gitignore style patterns of files that are not hashed
+const ignoreFileName = ".verifyignore"
+
+// ignoreRule is one gitignore style pattern
+type ignoreRule struct {
+	pattern string
+	regexp  *regexp.Regexp
+	negate  bool // the pattern started with "!", so it includes files that were ignored
+	dirOnly bool // the pattern ended with "/", so it only matches folders
+}
+
+// parseIgnoreRule compiles a gitignore pattern, ok is false for blank lines and comments
+func parseIgnoreRule(line string) (rule *ignoreRule, ok bool, err error) {
+	line = trimIgnoreLine(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, false, nil
+	}
+	rule = &ignoreRule{pattern: line}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil, false, nil
+	}
+	// a pattern with a slash is relative to the folder of the ignore file, otherwise it matches at any depth
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	rule.regexp, err = regexp.Compile("^" + ignorePatternRegexp(line) + "$")
+	if err != nil {
+		return nil, false, fmt.Errorf("Bad ignore pattern %q: %v", rule.pattern, err)
+	}
+	return rule, true, nil
+}
+
+// trimIgnoreLine removes trailing spaces, unless they are escaped with a backslash
+func trimIgnoreLine(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// ignorePatternRegexp converts a gitignore glob into a regular expression
+func ignorePatternRegexp(pattern string) string {
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				re.WriteString("\\[")
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.Replace(class, "\\", "\\\\", -1) + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
+
+// match returns true if the rule matches the file name, which is relative to the folder of the ignore file
+func (r *ignoreRule) match(fileName string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return r.regexp.MatchString(fileName)
+}
+
+// ignoreRules are the rules from one ignore file, and the folder they are relative to
+type ignoreRules struct {
+	dirName string // relative to the root folder with forward slashes, "" for the root folder
+	rules   []*ignoreRule
+}
+
+// loadIgnoreRules reads the ignore file in a folder, if there is one.
+// Like git, patterns that don't make sense are skipped.
+func loadIgnoreRules(fileName, dirName string) *ignoreRules {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	rules := &ignoreRules{dirName: dirName}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok, err := parseIgnoreRule(scanner.Text()); ok && err == nil {
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+	return rules
+}
+
+// fileFilter decides which files are hashed using the .verifyignore files, and the -exclude and -include flags
+type fileFilter struct {
+	excludeNames []string
+	excludes     []*ignoreRule // from -exclude, they are checked after every .verifyignore
+	includes     []*ignoreRule // from -include, if there are any then only files that match one are hashed
+	dirs         bool          // folders are sent on to be recorded in the manifest
+	folders      map[string]*ignoreRules
+	ignoredDirs  map[string]bool
+}
+
+// newFileFilter loads the .verifyignore in rootDir, and compiles the -exclude and -include patterns
+// `excludeNames` are never hashed, like the manifest and the cache
+func newFileFilter(rootDir string, excludeNames, excludes, includes []string) (*fileFilter, error) {
+	f := &fileFilter{
+		excludeNames: excludeNames,
+		folders:      map[string]*ignoreRules{},
+		ignoredDirs:  map[string]bool{},
+	}
+	var err error
+	if f.excludes, err = parseIgnoreFlags(excludes); err != nil {
+		return nil, err
+	}
+	if f.includes, err = parseIgnoreFlags(includes); err != nil {
+		return nil, err
+	}
+	if rules := loadIgnoreRules(filepath.Join(rootDir, ignoreFileName), ""); rules != nil {
+		f.folders[""] = rules
+	}
+	return f, nil
+}
+
+// parseIgnoreFlags compiles the patterns from the command line
+func parseIgnoreFlags(patterns []string) ([]*ignoreRule, error) {
+	var rules []*ignoreRule
+	for _, pattern := range patterns {
+		rule, ok, err := parseIgnoreRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// ignored returns true if the file or folder is ignored by the .verifyignore files or -exclude.
+// Folders must be checked before the files inside them, the same order as filepath.Walk, so it's called by walkFolder.
+func (f *fileFilter) ignored(file *pathFileInfo) bool {
+	parent := path.Dir(file.name)
+	if parent == "." {
+		parent = ""
+	}
+	if f.ignoredDirs[parent] {
+		if file.IsDir() {
+			f.ignoredDirs[file.name] = true
+		}
+		return true
+	}
+	ignored := false
+	// rules in deeper folders come later, so they take precedence
+	for _, dirName := range parentDirs(parent) {
+		rules, ok := f.folders[dirName]
+		if !ok {
+			continue
+		}
+		relName := file.name
+		if rules.dirName != "" {
+			relName = strings.TrimPrefix(file.name, rules.dirName+"/")
+		}
+		for _, rule := range rules.rules {
+			if rule.match(relName, file.IsDir()) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	for _, rule := range f.excludes {
+		if rule.match(file.name, file.IsDir()) {
+			ignored = !rule.negate
+		}
+	}
+	if file.IsDir() {
+		if ignored {
+			f.ignoredDirs[file.name] = true
+		} else if rules := loadIgnoreRules(filepath.Join(file.path, ignoreFileName), file.name); rules != nil {
+			f.folders[file.name] = rules
+		}
+	}
+	return ignored
+}
+
+// parentDirs lists the root folder "" and every folder down to dirName, ex. "a/b" is "", "a", "a/b"
+func parentDirs(dirName string) []string {
+	dirs := []string{""}
+	if dirName == "" {
+		return dirs
+	}
+	parts := strings.Split(dirName, "/")
+	for i := range parts {
+		dirs = append(dirs, strings.Join(parts[:i+1], "/"))
+	}
+	return dirs
+}
+
+// included returns true if there are no -include patterns, or the file matches them.
+// Like ignored the last pattern that matches wins, and a pattern that matches a folder matches every file in it.
+func (f *fileFilter) included(file *pathFileInfo) bool {
+	if len(f.includes) == 0 {
+		return true
+	}
+	parent := path.Dir(file.name)
+	if parent == "." {
+		parent = ""
+	}
+	included := false
+	for _, rule := range f.includes {
+		match := rule.match(file.name, file.IsDir())
+		for _, dirName := range parentDirs(parent)[1:] {
+			match = match || rule.match(dirName, true)
+		}
+		if match {
+			included = !rule.negate
+		}
+	}
+	return included
+}
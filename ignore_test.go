@@ -0,0 +1,213 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_parseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		fileName string
+		isDir    bool
+		match    bool
+	}{
+		{"*.tmp", "a.tmp", false, true},
+		{"*.tmp", "deep/down/a.tmp", false, true},
+		{"*.tmp", "a.tmp.txt", false, false},
+		{"/a.txt", "a.txt", false, true},
+		{"/a.txt", "sub/a.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "sub/build", true, true},
+		{"doc/*.txt", "doc/a.txt", false, true},
+		{"doc/*.txt", "doc/sub/a.txt", false, false},
+		{"doc/*.txt", "sub/doc/a.txt", false, false},
+		{"**/logs", "a/b/logs", true, true},
+		{"logs/**", "logs/a/b.txt", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"file?.txt", "file1.txt", false, true},
+		{"file?.txt", "file10.txt", false, false},
+		{"file[0-9].txt", "file5.txt", false, true},
+		{"file[!0-9].txt", "file5.txt", false, false},
+		{"\\#hash", "#hash", false, true},
+		{"trailing   ", "trailing", false, true},
+	}
+	for _, test := range tests {
+		// WHEN the pattern is parsed
+		rule, ok, err := parseIgnoreRule(test.pattern)
+		if err != nil || !ok {
+			t.Errorf("%q should be a rule: %v", test.pattern, err)
+			continue
+		}
+
+		// THEN it only matches the right files
+		if match := rule.match(test.fileName, test.isDir); match != test.match {
+			t.Errorf("%q matching %v should be %v", test.pattern, test.fileName, test.match)
+		}
+	}
+}
+
+func Test_parseIgnoreRule_Skipped(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		// WHEN a line without a pattern is parsed
+		_, ok, err := parseIgnoreRule(line)
+
+		// THEN it isn't a rule
+		if ok || err != nil {
+			t.Errorf("%q should be skipped: %v", line, err)
+		}
+	}
+}
+
+func Test_hashFolder_VerifyIgnore(t *testing.T) {
+	// GIVEN a folder with .verifyignore files in it, and in a sub folder
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{
+		".verifyignore":        "*.log\n!keep.log\nbuild/\n",
+		"a.txt":                "a",
+		"debug.log":            "log",
+		"keep.log":             "keep",
+		"build/out.bin":        "out",
+		"sub/.verifyignore":    "*.txt\n!/b.txt\n",
+		"sub/b.txt":            "b",
+		"sub/c.txt":            "c",
+		"sub/other.log":        "log",
+		"sub/deeper/d.txt":     "d",
+		"other/sub/e.txt":      "e",
+		"other/sub/ignore.tmp": "tmp",
+	})
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+	h.excludes = []string{"*.tmp"}
+
+	// WHEN the folder is hashed
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN only the files that aren't ignored are in the manifest
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".verifyignore", "a.txt", "keep.log", "other/sub/e.txt", "sub/.verifyignore", "sub/b.txt"}
+	if len(m) != len(expected) {
+		t.Errorf("Expected %v got %v", expected, m)
+	}
+	for _, name := range expected {
+		if _, ok := m[name]; !ok {
+			t.Errorf("%v should be in the manifest %v", name, m)
+		}
+	}
+}
+
+func Test_hashFolder_Include(t *testing.T) {
+	// GIVEN a folder with files in it, and in a sub folder
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "b.txt": "b", "sub/a.txt": "a"})
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+	h.includes = []string{"/a.txt"}
+
+	// WHEN the folder is hashed with an include pattern
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN only the included file is in the manifest
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["a.txt"]; !ok || len(m) != 1 {
+		t.Errorf("Only a.txt should be in the manifest %v", m)
+	}
+}
+
+func Test_hashFolder_IncludeFolder(t *testing.T) {
+	tests := []struct {
+		includes []string
+		dirs     bool
+		expected []string
+	}{
+		{[]string{"photos/"}, false, []string{"photos/a.jpg", "photos/2020/b.jpg", "photos/raw/c.cr2"}},
+		{[]string{"photos/", "!raw/"}, false, []string{"photos/a.jpg", "photos/2020/b.jpg"}},
+		{[]string{"!raw/", "photos/"}, false, []string{"photos/a.jpg", "photos/2020/b.jpg", "photos/raw/c.cr2"}},
+		{[]string{"*.jpg", "!photos/2020/"}, false, []string{"photos/a.jpg", "other/d.jpg"}},
+		{[]string{"photos/"}, true, []string{"photos", "photos/2020", "photos/raw", "photos/a.jpg", "photos/2020/b.jpg", "photos/raw/c.cr2"}},
+	}
+	for _, test := range tests {
+		// GIVEN a folder with a photos folder, and a file that's named photos
+		dirName := t.TempDir()
+		writeTestFiles(t, dirName, map[string]string{
+			"a.txt":             "a",
+			"photos/a.jpg":      "a",
+			"photos/2020/b.jpg": "b",
+			"photos/raw/c.cr2":  "c",
+			"other/photos":      "not a folder",
+			"other/d.jpg":       "d",
+		})
+		_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+		h.algorithms = []string{manifest.MD5}
+		h.includes = test.includes
+		h.dirs = test.dirs
+
+		// WHEN the folder is hashed with include patterns
+		if err := h.HashFolder(dirName); err != nil {
+			t.Fatal(err, errorBuffer)
+		}
+
+		// THEN the last pattern that matches a file, or a folder it's in, decides if it's included
+		m := manifest.Manifest{}
+		if err := m.Load(dirName, "manifest.json"); err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != len(test.expected) {
+			t.Errorf("%v expected %v got %v", test.includes, test.expected, m.FileNames())
+		}
+		for _, name := range test.expected {
+			if _, ok := m[name]; !ok {
+				t.Errorf("%v should include %v got %v", test.includes, name, m.FileNames())
+			}
+		}
+	}
+}
+
+func Test_hashFolder_BadExclude(t *testing.T) {
+	// GIVEN an exclude pattern that isn't valid
+	_, _, h := makeTestFolderHasher("manifest.json", "")
+	h.excludes = []string{"[z-a]"}
+
+	// WHEN the folder is hashed
+	err := h.HashFolder(t.TempDir())
+
+	// THEN it should fail before hashing anything
+	if err == nil {
+		t.Error("A bad pattern should be an error")
+	}
+}
+
+func Test_hashFolder_IgnoredFolderNotWalked(t *testing.T) {
+	// GIVEN an ignored folder with a link inside it that can't be followed
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "build/out.bin": "out", ignoreFileName: "build/\n"})
+	if err := os.Symlink("nowhere", filepath.Join(dirName, "build", "stale")); err != nil {
+		t.Skip("Symbolic links aren't supported:", err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+
+	// WHEN the folder is hashed
+	err := h.HashFolder(dirName)
+
+	// THEN the ignored folder isn't walked, so the link doesn't matter
+	if err != nil {
+		t.Errorf("The ignored folder shouldn't be walked: %v %v", err, errorBuffer)
+	}
+}
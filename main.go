@@ -1,16 +1,21 @@
 // Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
 
 // Command VerifyManifest helps create a new `manifest.json` file in a folder, and verify an existing manifest.
-// Each file is checked for `SHA1` and `MD5` hashes.
+// Each file is checked for `MD5`, `SHA1`, `SHA256` and `SHA512` hashes, or the algorithms chosen with `-algorithms`.
 //
 // This is helpful to see if the contents of a file have changed since the last time the tool was run.
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io"
 	"log"
 	"os"
+	"runtime"
+	"strings"
 )
 
 const verifyManifestVersion = "v0.2"
@@ -20,32 +25,172 @@ type commandFlag struct {
 	RootDir          string
 	ManifestFilename string
 	UnknownFilename  string
+	Algorithms       string
+	VerifyOnly       bool
+	Strict           bool
+	Jobs             int
+	CacheFilename    string
+	Quick            bool
+	Full             bool
+	Format           string
+	Paths            string
+	Excludes         stringList
+	Includes         stringList
+	Symlinks         string
+	Meta             string
+	CheckMeta        string
+	Dirs             bool
+	AcceptMoves      bool
+	Report           string
+	ReportFilename   string
 	infoLog          *log.Logger
 	errorLog         *log.Logger
 	exit             func(code int)
+	output           io.Writer // where a report goes without -report-file
 }
 
 var gFlags = commandFlag{}
 
+// stringList is a flag that can be repeated, ex. `-exclude '*.tmp' -exclude 'cache/'`
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func init() {
 	flag.StringVar(&gFlags.RootDir, "root", ".", "Root folder to calculate Sum.")
 	flag.StringVar(&gFlags.ManifestFilename, "manifest", "manifest.json", "Manifest file name.")
 	flag.StringVar(&gFlags.UnknownFilename, "unknown", "", "A text manifest file that contains hash sums in an unknown format.  Every sum in \"unknown\" file must be present in directory to pass.")
+	flag.StringVar(&gFlags.Algorithms, "algorithms", strings.Join(manifest.DefaultAlgorithms, ","), "Comma separated hash algorithms to calculate, any of "+strings.Join(manifest.Algorithms(), ","))
+	flag.BoolVar(&gFlags.VerifyOnly, "verify", false, "Only verify the files against an existing manifest, never write the manifest.")
+	flag.BoolVar(&gFlags.Strict, "strict", false, "Fail if a file is not in the manifest, new files are as suspicious as changed ones.")
+	flag.IntVar(&gFlags.Jobs, "jobs", 1, "How many files to hash at the same time, 0 uses every CPU.")
+	flag.StringVar(&gFlags.CacheFilename, "cache", "", "Cache file that remembers the size, modified time and inode of each file.  Defaults to the manifest name + \".cache\" in the root folder.")
+	flag.BoolVar(&gFlags.Quick, "quick", false, "Skip hashing files that are unchanged in the cache.")
+	flag.BoolVar(&gFlags.Full, "full", false, "Hash every file, ignoring the cache, and save a fresh cache.  Use for a periodic scrub with -quick the rest of the time.")
+	flag.StringVar(&gFlags.Format, "format", "json", "Format the manifest is saved in: json, gnu, md5sum, sha1sum, sha256sum, sha512sum or bsd.  Existing manifests are read in any format, and saved in the same format without -format.")
+	flag.StringVar(&gFlags.Paths, "paths", "exact", "How file names are matched to the manifest: exact, nfc (Unicode canonical equivalence, for manifests made on macOS) or case (canonical equivalence and case-insensitive).")
+	flag.StringVar(&gFlags.Symlinks, "symlinks", "follow", "What to do with symbolic links: follow (hash what they point to), skip, or record (save the link's target instead of a hash).")
+	flag.StringVar(&gFlags.Meta, "meta", "", "Comma separated metadata to save in the manifest with the hashes, any of "+strings.Join(manifest.MetadataAttributes, ",")+".")
+	flag.StringVar(&gFlags.CheckMeta, "check-meta", "", "Comma separated metadata that must match the manifest, ex. mode,owner.  They are saved in the manifest too.")
+	flag.BoolVar(&gFlags.Dirs, "dirs", false, "Record folders in the manifest, so a folder that is removed or added fails like a file.  Use -meta mode to record their permissions too.")
+	flag.BoolVar(&gFlags.AcceptMoves, "accept-moves", false, "A file that was moved or renamed without changing its contents doesn't fail.")
+	flag.StringVar(&gFlags.Report, "report", "text", "Write a report of every file for other programs: text (only the log), json, junit or tap.")
+	flag.StringVar(&gFlags.ReportFilename, "report-file", "", "File the report is written to, instead of stdout.  When the report is on stdout the log goes to stderr.")
+	flag.Var(&gFlags.Excludes, "exclude", "Pattern of files not to hash, with the same rules as a line in "+ignoreFileName+".  Can be repeated.")
+	flag.Var(&gFlags.Includes, "include", "Pattern of files to hash, every other file is skipped.  Can be repeated.")
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\nVersion %s\n%s\n\n", os.Args[0], verifyManifestVersion, verifyManifestWebsite)
+		fmt.Fprintf(os.Stderr, "Usage of %s: [flags] [command [command flags]]\nVersion %s\n%s\n\n", os.Args[0], verifyManifestVersion, verifyManifestWebsite)
 		flag.PrintDefaults()
+		printCommands(os.Stderr)
 	}
 	gFlags.infoLog = log.New(os.Stdout, "", 0)
 	gFlags.errorLog = log.New(os.Stderr, "", 0)
 	gFlags.exit = os.Exit
+	gFlags.output = os.Stdout
 }
 
 func main() {
 	flag.Parse()
-	hasher := NewFolderHasher(gFlags.ManifestFilename, gFlags.UnknownFilename, gFlags.infoLog, gFlags.errorLog)
-	err := hasher.HashFolder(gFlags.RootDir)
+	hasher, err := newFlagHasher()
+	if err != nil {
+		gFlags.errorLog.Print(err)
+		gFlags.exit(exitUsage)
+		return
+	}
+	if flag.NArg() > 0 {
+		if code := runCommand(hasher, gFlags.RootDir, flag.Arg(0), flag.Args()[1:]); code != exitOK {
+			gFlags.exit(code)
+		}
+		return
+	}
+	err = hasher.HashFolder(gFlags.RootDir)
 	if err != nil {
 		gFlags.errorLog.Print(err)
-		gFlags.exit(1)
+		gFlags.exit(exitCode(err))
+	}
+}
+
+// newFlagHasher makes a folderHasher with the options from the command line flags
+func newFlagHasher() (*folderHasher, error) {
+	algorithms, err := manifest.ParseAlgorithms(gFlags.Algorithms)
+	if err != nil {
+		return nil, err
+	}
+	format, err := manifest.ParseFormat(gFlags.Format)
+	if err != nil {
+		return nil, err
 	}
+	pathPolicy, err := manifest.ParsePathPolicy(gFlags.Paths)
+	if err != nil {
+		return nil, err
+	}
+	symlinks, err := parseSymlinkPolicy(gFlags.Symlinks)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := manifest.ParseAttributes(gFlags.Meta)
+	if err != nil {
+		return nil, err
+	}
+	checkMeta, err := manifest.ParseAttributes(gFlags.CheckMeta)
+	if err != nil {
+		return nil, err
+	}
+	report, err := parseReportFormat(gFlags.Report)
+	if err != nil {
+		return nil, err
+	}
+	if gFlags.Quick && gFlags.Full {
+		return nil, errors.New("Only one of -quick or -full can be used")
+	}
+	infoLog := gFlags.infoLog
+	if report != reportText && gFlags.ReportFilename == "" {
+		// the report is on stdout, so the log can't be
+		infoLog = gFlags.errorLog
+	}
+	hasher := NewFolderHasher(gFlags.ManifestFilename, gFlags.UnknownFilename, infoLog, gFlags.errorLog)
+	hasher.algorithms = algorithms
+	hasher.verifyOnly = gFlags.VerifyOnly
+	hasher.strict = gFlags.Strict
+	hasher.jobs = gFlags.Jobs
+	if hasher.jobs == 0 {
+		hasher.jobs = runtime.NumCPU()
+	}
+	hasher.cacheFileName = gFlags.CacheFilename
+	hasher.quick = gFlags.Quick
+	hasher.full = gFlags.Full
+	if flagSet("format") {
+		// without -format the manifest is saved in the format it was loaded in
+		hasher.format = format
+	}
+	hasher.pathPolicy = pathPolicy
+	hasher.excludes = gFlags.Excludes
+	hasher.includes = gFlags.Includes
+	hasher.symlinks = symlinks
+	hasher.meta = meta
+	hasher.checkMeta = checkMeta
+	hasher.dirs = gFlags.Dirs
+	hasher.acceptMoves = gFlags.AcceptMoves
+	hasher.report = report
+	hasher.reportFileName = gFlags.ReportFilename
+	hasher.reportOutput = gFlags.output
+	return hasher, nil
+}
+
+// flagSet returns true if the flag was given on the command line, not just its default
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
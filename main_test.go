@@ -68,3 +68,35 @@ func Test_Main_Error(t *testing.T) {
 		t.Error("Nothing should be output, because it should have failed.")
 	}
 }
+
+func Test_Main_BadAlgorithm(t *testing.T) {
+	// GIVEN an algorithm that isn't registered
+	gFlags.RootDir = "test_data"
+	gFlags.UnknownFilename = ""
+	gFlags.Algorithms = "md5,noexist"
+	defer func() { gFlags.Algorithms = "MD5,SHA1,SHA256,SHA512" }()
+	infoBuffer := &bytes.Buffer{}
+	errorBuffer := &bytes.Buffer{}
+	gFlags.infoLog = log.New(infoBuffer, "", 0)
+	gFlags.errorLog = log.New(errorBuffer, "", 0)
+	exitCode := 0
+	gFlags.exit = func(code int) {
+		exitCode = code
+	}
+
+	// WHEN VerifyManifest is run
+	main()
+
+	// THEN it should exit non-zero (failure) and say which algorithm
+	if exitCode == 0 {
+		t.Error("Expected a non-zero exit code")
+	}
+	if !strings.Contains(errorBuffer.String(), "noexist") {
+		t.Errorf("The error should mention the algorithm: %v", errorBuffer.String())
+	}
+
+	// THEN nothing should have been hashed
+	if infoBuffer.Len() > 0 {
+		t.Errorf("Nothing should be output: %v", infoBuffer.String())
+	}
+}
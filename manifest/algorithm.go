@@ -0,0 +1,130 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// Names of the built in hash algorithms, these are also the keys used in a Sum.
+const (
+	MD5    = "MD5"
+	SHA1   = "SHA1"
+	SHA256 = "SHA256"
+	SHA512 = "SHA512"
+)
+
+// DefaultAlgorithms are calculated when no algorithms are chosen.
+var DefaultAlgorithms = []string{MD5, SHA1, SHA256, SHA512}
+
+// Hasher is a hash algorithm that can be registered and used to calculate a Sum.
+type Hasher interface {
+	// Name is the key of the digest in a Sum, ex. "SHA256".
+	Name() string
+	// New returns a new hash.Hash ready to calculate a digest.
+	New() hash.Hash
+	// HexLen is how many hex characters are in the digest.
+	HexLen() int
+}
+
+type algorithm struct {
+	name   string
+	new    func() hash.Hash
+	hexLen int
+}
+
+func (a *algorithm) Name() string   { return a.name }
+func (a *algorithm) New() hash.Hash { return a.new() }
+func (a *algorithm) HexLen() int    { return a.hexLen }
+
+var registry = struct {
+	sync.RWMutex
+	hashers map[string]Hasher
+	order   []string
+}{
+	hashers: map[string]Hasher{},
+}
+
+func init() {
+	RegisterAlgorithm(MD5, md5.New, 32)
+	RegisterAlgorithm(SHA1, sha1.New, 40)
+	RegisterAlgorithm(SHA256, sha256.New, 64)
+	RegisterAlgorithm(SHA512, sha512.New, 128)
+}
+
+// Register adds a Hasher so it can be used by Sum.Calculate and found in unknown hash files.
+// Registering a name that already exists replaces the previous Hasher.
+func Register(h Hasher) {
+	registry.Lock()
+	defer registry.Unlock()
+	key := strings.ToUpper(h.Name())
+	if _, ok := registry.hashers[key]; !ok {
+		registry.order = append(registry.order, h.Name())
+	}
+	registry.hashers[key] = h
+}
+
+// RegisterAlgorithm registers a hash constructor, ex. `RegisterAlgorithm("CRC32", func() hash.Hash { return crc32.NewIEEE() }, 8)`
+func RegisterAlgorithm(name string, new func() hash.Hash, hexLen int) {
+	Register(&algorithm{
+		name:   name,
+		new:    new,
+		hexLen: hexLen,
+	})
+}
+
+// unregister removes a Hasher, so a test can register one without changing the other tests.
+func unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	key := strings.ToUpper(name)
+	delete(registry.hashers, key)
+	for i, other := range registry.order {
+		if strings.ToUpper(other) == key {
+			registry.order = append(registry.order[:i:i], registry.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Lookup finds a registered Hasher by name, ignoring case.
+func Lookup(name string) (h Hasher, ok bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	h, ok = registry.hashers[strings.ToUpper(name)]
+	return
+}
+
+// Algorithms returns the names of every registered algorithm in the order they were registered.
+func Algorithms() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]string{}, registry.order...)
+}
+
+// ParseAlgorithms turns a comma separated list like "md5,sha256" into registered algorithm names.
+func ParseAlgorithms(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		h, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown hash algorithm %v, expected one of %v", name, strings.Join(Algorithms(), ","))
+		}
+		names = append(names, h.Name())
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No hash algorithms in %q", list)
+	}
+	return names, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func Test_Lookup(t *testing.T) {
+	// GIVEN the built in algorithms
+	// WHEN one is looked up with a different case
+	h, ok := Lookup("sha256")
+
+	// THEN it should be found with the canonical name
+	if !ok {
+		t.Fatal("sha256 should have been registered")
+	}
+	if h.Name() != SHA256 || h.HexLen() != 64 {
+		t.Errorf("Unexpected hasher %v %v", h.Name(), h.HexLen())
+	}
+
+	// WHEN an algorithm that isn't registered is looked up
+	// THEN it shouldn't be found
+	if _, ok := Lookup("noexist"); ok {
+		t.Error("noexist should not have been registered")
+	}
+}
+
+func Test_RegisterAlgorithm(t *testing.T) {
+	// GIVEN a new algorithm is registered
+	RegisterAlgorithm("CRC32", func() hash.Hash { return crc32.NewIEEE() }, 8)
+	t.Cleanup(func() { unregister("CRC32") })
+
+	// WHEN a sum is calculated with it
+	sum := Sum{}
+	if err := sum.Calculate("../test_data/a.txt", "crc32", MD5); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN only the chosen algorithms should be in the sum
+	if len(sum) != 2 {
+		t.Errorf("Expected 2 hashes got %v", sum)
+	}
+	crc := "e8b7be43"
+	if sum["CRC32"] != crc {
+		t.Errorf("Expected CRC32 %v got %v", crc, sum["CRC32"])
+	}
+
+	// THEN the algorithm should be in the list of algorithms after the built in ones
+	names := Algorithms()
+	if names[len(names)-1] != "CRC32" {
+		t.Errorf("CRC32 should have been registered last %v", names)
+	}
+}
+
+func Test_ParseAlgorithms(t *testing.T) {
+	// GIVEN a comma separated list with odd spacing and case
+	// WHEN it is parsed
+	names, err := ParseAlgorithms(" md5, SHA512 ,")
+
+	// THEN it should return the canonical names
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != MD5 || names[1] != SHA512 {
+		t.Errorf("Unexpected algorithms %v", names)
+	}
+
+	// WHEN an unknown algorithm is in the list
+	// THEN it should error
+	if _, err := ParseAlgorithms("md5,noexist"); err == nil {
+		t.Error("Expected an error for an unknown algorithm")
+	}
+
+	// WHEN the list is empty
+	// THEN it should error
+	if _, err := ParseAlgorithms(""); err == nil {
+		t.Error("Expected an error for an empty list")
+	}
+}
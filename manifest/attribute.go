@@ -0,0 +1,166 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Attributes are stored in a Sum next to the hashes, their names are lower case so they never collide with an algorithm.
+const (
+	// Link is the target of a symbolic link, a Sum with a Link has no hashes.
+	Link = "link"
+	// Size is the size of the file in bytes.
+	Size = "size"
+	// Mode is the file's permissions in octal like chmod, ex. "0755".
+	Mode = "mode"
+	// ModTime is when the file was last modified, in RFC 3339 format in UTC.
+	ModTime = "mtime"
+	// Owner is the user and group id of the file, ex. "1000:1000".  It is empty on systems without them.
+	Owner = "owner"
+	// Type is TypeDir for a folder, a Sum with a Type has no hashes.
+	Type = "type"
+)
+
+// TypeDir is the Type of a folder.
+const TypeDir = "dir"
+
+// attributes are the names in a Sum that are not hash algorithms
+var attributes = map[string]bool{
+	Link:    true,
+	Size:    true,
+	Mode:    true,
+	ModTime: true,
+	Owner:   true,
+	Type:    true,
+}
+
+// MetadataAttributes are the attributes that can be read from a file's info, in the order they are checked.
+var MetadataAttributes = []string{Size, Mode, ModTime, Owner}
+
+// IsAttribute returns true if the name in a Sum is an attribute like Link, and not a hash algorithm.
+func IsAttribute(name string) bool {
+	return attributes[name]
+}
+
+// Attributes returns the names of the attributes in the sum, alphabetically.
+func (s Sum) Attributes() []string {
+	var names []string
+	for name := range s {
+		if IsAttribute(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsLink returns true if the sum is for a symbolic link, instead of the contents of a file.
+func (s Sum) IsLink() bool {
+	return s[Link] != ""
+}
+
+// IsDir returns true if the sum is for a folder, instead of the contents of a file.
+func (s Sum) IsDir() bool {
+	return s[Type] == TypeDir
+}
+
+// ReadLink sets the sum to the target of the symbolic link, instead of hashing the file it points to.
+func (s *Sum) ReadLink(fileName string) error {
+	target, err := os.Readlink(fileName)
+	if err != nil {
+		return err
+	}
+	*s = Sum{Link: filepath.ToSlash(target)}
+	return nil
+}
+
+// ParseAttributes returns the metadata attribute names in a comma separated list, ex. "mode,owner".
+func ParseAttributes(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, attribute := range MetadataAttributes {
+			if name == attribute {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown attribute %v, expected any of %v", name, strings.Join(MetadataAttributes, ","))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// SetAttributes sets the named metadata attributes from the file's info.
+func (s Sum) SetAttributes(info os.FileInfo, names ...string) {
+	for _, name := range names {
+		var value string
+		switch name {
+		case Size:
+			value = fmt.Sprintf("%d", info.Size())
+		case Mode:
+			value = fileMode(info.Mode())
+		case ModTime:
+			value = info.ModTime().UTC().Format(time.RFC3339Nano)
+		case Owner:
+			value = owner(info)
+		}
+		if value != "" {
+			s[name] = value
+		}
+	}
+}
+
+// fileMode formats the permissions like chmod, including the setuid, setgid and sticky bits
+func fileMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
+
+// Hashes returns a copy of the sum with only the hashes, and none of the attributes.
+func (s Sum) Hashes() Sum {
+	hashes := make(Sum, len(s))
+	for _, name := range s.Algorithms() {
+		hashes[name] = s[name]
+	}
+	return hashes
+}
+
+// VerifyAttributes compares the named attributes that both sums have, every one that is different is in the error.
+func (s Sum) VerifyAttributes(other Sum, names ...string) error {
+	var mismatches []string
+	for _, name := range names {
+		theirs, ok := other[name]
+		if !ok || theirs == "" || s[name] == "" {
+			continue
+		}
+		if s[name] != theirs {
+			mismatches = append(mismatches, fmt.Sprintf("%v mismatch %v != %v", name, s[name], theirs))
+		}
+	}
+	if len(mismatches) > 0 {
+		return errors.New(strings.Join(mismatches, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Sum_Verify_Link(t *testing.T) {
+	tests := []struct {
+		expected Sum
+		actual   Sum
+		err      string
+	}{
+		{Sum{Link: "a.txt"}, Sum{Link: "a.txt"}, ""},
+		{Sum{Link: "a.txt"}, Sum{Link: "b.txt"}, "link mismatch a.txt != b.txt"},
+		{Sum{Link: "a.txt"}, Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"}, "Expected a link to a.txt, found a file"},
+		{Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"}, Sum{Link: "a.txt"}, "Expected a file, found a link to a.txt"},
+	}
+	for _, test := range tests {
+		// WHEN the sums are verified
+		err := test.expected.Verify(test.actual)
+
+		// THEN links are compared by their target
+		if test.err == "" && err != nil {
+			t.Errorf("%v and %v should match: %v", test.expected, test.actual, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%v and %v should fail with %q: %v", test.expected, test.actual, test.err, err)
+		}
+	}
+}
+
+func Test_Sum_Attributes(t *testing.T) {
+	// GIVEN a sum with a link
+	sum := Sum{Link: "a.txt"}
+
+	// THEN the link isn't a hash algorithm
+	if algorithms := sum.Algorithms(); len(algorithms) != 0 {
+		t.Errorf("A link has no algorithms, got %v", algorithms)
+	}
+	if s := sum.String(); s != "link:a.txt" {
+		t.Errorf("Expected link:a.txt got %v", s)
+	}
+}
+
+func Test_ParseAttributes(t *testing.T) {
+	// WHEN a list of attributes is parsed
+	names, err := ParseAttributes("Mode, owner,,size")
+
+	// THEN they are lower case in the same order
+	if err != nil || strings.Join(names, ",") != "mode,owner,size" {
+		t.Errorf("Expected mode,owner,size got %v %v", names, err)
+	}
+
+	// WHEN an attribute is unknown
+	if _, err := ParseAttributes("mode,color"); err == nil {
+		t.Error("An unknown attribute should be an error")
+	}
+}
+
+func Test_Sum_SetAttributes(t *testing.T) {
+	// GIVEN an executable file
+	fileName := filepath.Join(t.TempDir(), "script.sh")
+	if err := ioutil.WriteFile(fileName, []byte("abc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(fileName, 0755); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN the attributes are set from the file's info
+	sum := Sum{MD5: "900150983cd24fb0d6963f7d28e17f72"}
+	sum.SetAttributes(info, Size, Mode, ModTime)
+
+	// THEN they are formatted like chmod and ls
+	if sum[Size] != "3" {
+		t.Errorf("Expected size 3 got %v", sum[Size])
+	}
+	if sum[Mode] != "0755" {
+		t.Errorf("Expected mode 0755 got %v", sum[Mode])
+	}
+	if sum[ModTime] == "" {
+		t.Error("Expected a modified time")
+	}
+
+	// THEN the hashes don't have the attributes
+	if hashes := sum.Hashes(); len(hashes) != 1 || hashes[MD5] != sum[MD5] {
+		t.Errorf("Expected only the MD5 got %v", hashes)
+	}
+}
+
+func Test_Sum_VerifyAttributes(t *testing.T) {
+	// GIVEN a sum with a mode and owner
+	expected := Sum{Mode: "0755", Owner: "0:0", Size: "3"}
+
+	// WHEN the mode and owner changed
+	err := expected.VerifyAttributes(Sum{Mode: "0644", Owner: "1000:1000", Size: "3"}, Mode, Owner, Size)
+
+	// THEN both are in the error
+	if err == nil || err.Error() != "mode mismatch 0755 != 0644, owner mismatch 0:0 != 1000:1000" {
+		t.Errorf("Expected the mode and owner to mismatch: %v", err)
+	}
+
+	// WHEN only attributes that weren't recorded are checked
+	if err := (Sum{Size: "3"}).VerifyAttributes(Sum{Mode: "0644", Size: "3"}, Mode, Size); err != nil {
+		t.Errorf("Attributes that weren't recorded should be skipped: %v", err)
+	}
+}
+
+func Test_Sum_Verify_Dir(t *testing.T) {
+	// GIVEN a folder
+	dir := Sum{Type: TypeDir, Mode: "0755"}
+
+	// THEN it matches another folder, but not a file
+	if err := dir.Verify(Sum{Type: TypeDir}); err != nil {
+		t.Errorf("Folders should match: %v", err)
+	}
+	if err := dir.Verify(Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"}); err == nil {
+		t.Error("A folder shouldn't match a file")
+	}
+	if err := (Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"}).Verify(dir); err == nil {
+		t.Error("A file shouldn't match a folder")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// BSDFormat is the tagged format of `shasum --tag`, FreeBSD `sha256` and `openssl dgst`.
+// Each line is "ALGO (path) = hex", so a file can have a line for each algorithm.
+type BSDFormat struct {
+	// Algorithm is the only hash written by Encode, if it's empty then every hash in the sum is written.
+	Algorithm string
+}
+
+// bsdLine matches "SHA256 (a.txt) = hex", and openssl's "SHA2-256(a.txt)= hex"
+var bsdLine = regexp.MustCompile(`^([A-Za-z0-9-]+) ?\((.*)\) ?= ?([0-9A-Fa-f]+)$`)
+
+// Decode reads every "ALGO (path) = hex" line into the manifest.
+func (f BSDFormat) Decode(r io.Reader, m *Manifest) error {
+	return decodeLines(r, m, parseBSDLine)
+}
+
+// parseBSDLine splits a line into the file name, the algorithm from the tag, and the hash
+func parseBSDLine(line string) (fileName, algorithm, hash string, err error) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+	match := bsdLine.FindStringSubmatch(line)
+	if match == nil {
+		return "", "", "", fmt.Errorf("expected \"ALGO (file) = hash\" but got %q", line)
+	}
+	fileName = match[2]
+	if escaped {
+		fileName = unescapeFileName(fileName)
+	}
+	hash = strings.ToLower(match[3])
+	algorithm, err = algorithmForTag(match[1])
+	if err != nil {
+		return "", "", "", err
+	}
+	if _, err = algorithmForHash(hash, algorithm); err != nil {
+		return "", "", "", err
+	}
+	return cleanFileName(fileName), algorithm, hash, nil
+}
+
+// Encode writes an "ALGO (path) = hex" line for every hash of every file, sorted by path.
+func (f BSDFormat) Encode(w io.Writer, m *Manifest) error {
+	bw := bufio.NewWriter(w)
+	for _, fileName := range m.FileNames() {
+		sum := (*m)[fileName]
+		algorithms := sum.Algorithms()
+		if len(algorithms) == 0 {
+			// links and folders can't be written in this format
+			continue
+		}
+		if f.Algorithm != "" {
+			if _, ok := sum[f.Algorithm]; !ok {
+				return fmt.Errorf("%v doesn't have a %v hash", fileName, f.Algorithm)
+			}
+			algorithms = []string{f.Algorithm}
+		}
+		prefix := ""
+		name := fileName
+		if escapedName := escapeFileName(fileName); escapedName != fileName {
+			prefix = "\\"
+			name = escapedName
+		}
+		for _, algorithm := range algorithms {
+			fmt.Fprintf(bw, "%v%v (%v) = %v\n", prefix, algorithm, name, sum[algorithm])
+		}
+	}
+	return bw.Flush()
+}
+
+// algorithmForTag finds the registered algorithm for a tag like "SHA256", "SHA-256" or openssl's "SHA2-256"
+func algorithmForTag(tag string) (string, error) {
+	if h, ok := Lookup(tag); ok {
+		return h.Name(), nil
+	}
+	name := strings.Replace(strings.ToUpper(tag), "SHA2-", "SHA", 1)
+	name = strings.Replace(name, "-", "", -1)
+	if h, ok := Lookup(name); ok {
+		return h.Name(), nil
+	}
+	return "", fmt.Errorf("Unknown hash algorithm %v", tag)
+}
+
+// isBSDLine returns true if the line looks like "ALGO (path) = hex"
+func isBSDLine(line string) bool {
+	return bsdLine.MatchString(strings.TrimPrefix(line, "\\"))
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_BSDFormat_Decode(t *testing.T) {
+	// GIVEN tagged lines from shasum --tag, openssl, and an escaped file name
+	text := "SHA256 (a.txt) = ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb\n" +
+		"MD5 (a.txt) = 0CC175B9C0F1B6A831C399E269772661\n" +
+		"SHA2-256(b (1).txt)= 3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d\n" +
+		"\\SHA1 (new\\nline.txt) = 86f7e437faa5a7fce15d1ddcb9eaeaea377667b8\n"
+
+	// WHEN it is decoded
+	m := Manifest{}
+	if err := (BSDFormat{}).Decode(strings.NewReader(text), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN each tag should be put in the right algorithm of the right file
+	a := m["a.txt"]
+	if a[SHA256] != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" || a[MD5] != "0cc175b9c0f1b6a831c399e269772661" {
+		t.Errorf("a.txt should have SHA256 and MD5 %v", a)
+	}
+	if m["b (1).txt"][SHA256] != "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d" {
+		t.Errorf("The openssl SHA2-256 line wasn't understood %v", m)
+	}
+	if m["new\nline.txt"][SHA1] != "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8" {
+		t.Errorf("The escaped file name wasn't unescaped %v", m)
+	}
+}
+
+func Test_BSDFormat_Decode_BadLine(t *testing.T) {
+	// GIVEN lines with a bad tag, a hash that's the wrong length for the tag, and no tag
+	for _, line := range []string{
+		"WHIRLPOOL (a.txt) = 0cc175b9c0f1b6a831c399e269772661",
+		"SHA256 (a.txt) = 0cc175b9c0f1b6a831c399e269772661",
+		"0cc175b9c0f1b6a831c399e269772661  a.txt",
+	} {
+		// WHEN it is decoded
+		m := Manifest{}
+		err := (BSDFormat{}).Decode(strings.NewReader(line), &m)
+
+		// THEN it should error
+		if err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
+
+func Test_BSDFormat_Encode(t *testing.T) {
+	// GIVEN a manifest with two algorithms
+	m := Manifest{
+		"a.txt": Sum{MD5: "0cc175b9c0f1b6a831c399e269772661", SHA1: "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"},
+	}
+
+	// WHEN it is encoded
+	buffer := &bytes.Buffer{}
+	if err := (BSDFormat{}).Encode(buffer, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN there should be a tagged line for each algorithm
+	expected := "MD5 (a.txt) = 0cc175b9c0f1b6a831c399e269772661\nSHA1 (a.txt) = 86f7e437faa5a7fce15d1ddcb9eaeaea377667b8\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected %q got %q", expected, buffer.String())
+	}
+}
+
+func Test_Manifest_Load_BSD(t *testing.T) {
+	// GIVEN a tagged file
+	// WHEN it is loaded as a manifest
+	m := Manifest{}
+	if err := m.Load("../test_data/other_manifests", "tagged.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN the format should be detected
+	if m["b.txt"][SHA1] != "e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98" {
+		t.Errorf("b.txt SHA1 wrong %v", m)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Cache remembers what each file looked like on disk the last time it was hashed.
+// If a file still looks the same, then it doesn't need to be hashed again.
+type Cache map[string]FileStat
+
+// FileStat is the size, modification time and inode of a file, used to guess if it changed without reading it.
+type FileStat struct {
+	Size    int64
+	ModTime time.Time
+	Inode   uint64 `json:",omitempty"`
+}
+
+// NewFileStat gets the FileStat from the file information.
+func NewFileStat(info os.FileInfo) FileStat {
+	return FileStat{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Inode:   inode(info),
+	}
+}
+
+// Equal returns true if both stats describe the same unchanged file.
+func (s FileStat) Equal(other FileStat) bool {
+	return s.Size == other.Size && s.ModTime.Equal(other.ModTime) && s.Inode == other.Inode
+}
+
+// Unchanged returns true if the file is in the cache and looks the same as when it was cached.
+func (c *Cache) Unchanged(fileName string, info os.FileInfo) bool {
+	cached, ok := (*c)[fileName]
+	return ok && cached.Equal(NewFileStat(info))
+}
+
+// Load the cache from a file.
+func (c *Cache) Load(fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("Couldn't open cache %v: %v", fileName, err)
+	}
+	defer file.Close()
+	dec := json.NewDecoder(file)
+	if err = dec.Decode(c); err != nil {
+		return fmt.Errorf("Couldn't understand cache file format %v: %v", fileName, err)
+	}
+	return nil
+}
+
+// Save the cache to a file.
+func (c *Cache) Save(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("Couldn't create cache file %v: %v", fileName, err)
+	}
+	defer file.Close()
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "\t")
+	return enc.Encode(c)
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_Cache_SaveLoad(t *testing.T) {
+	// GIVEN a cache with a file in it
+	info, err := os.Stat("../test_data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Cache{"a.txt": NewFileStat(info)}
+	fileName := path.Join(t.TempDir(), "manifest.json.cache")
+
+	// WHEN the cache is saved and loaded
+	if err := c.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	loaded := Cache{}
+	if err := loaded.Load(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN the file should be unchanged
+	if !loaded.Unchanged("a.txt", info) {
+		t.Errorf("a.txt should be unchanged in the cache %v", loaded)
+	}
+
+	// THEN a different file should not be unchanged
+	other, err := os.Stat("../test_data/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Unchanged("b.txt", other) {
+		t.Error("b.txt isn't in the cache")
+	}
+	loaded["b.txt"] = FileStat{Size: other.Size() + 1, ModTime: other.ModTime()}
+	if loaded.Unchanged("b.txt", other) {
+		t.Error("b.txt has a different size in the cache")
+	}
+}
+
+func Test_Cache_Load_FileError(t *testing.T) {
+	// GIVEN a cache file that doesn't exist
+	// WHEN it is loaded
+	c := Cache{}
+	err := c.Load("noexist")
+
+	// THEN an error should happen
+	if err == nil {
+		t.Error("Expected an error loading noexist")
+	}
+}
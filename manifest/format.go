@@ -0,0 +1,103 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format reads and writes a manifest file in a specific file format.
+type Format interface {
+	// Decode reads the manifest entries from r into m.
+	Decode(r io.Reader, m *Manifest) error
+	// Encode writes every entry in m to w.
+	Encode(w io.Writer, m *Manifest) error
+}
+
+// JSONFormat is the default `manifest.json` format, a JSON object of file names to sums.
+type JSONFormat struct{}
+
+// Decode reads a JSON manifest.
+func (JSONFormat) Decode(r io.Reader, m *Manifest) error {
+	return json.NewDecoder(r).Decode(m)
+}
+
+// Encode writes a tab indented JSON manifest.
+func (JSONFormat) Encode(w io.Writer, m *Manifest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// ParseFormat returns the Format for a name used on the command line.
+// "json" is the default, "gnu" is a GNU coreutils file with the longest hash of each file,
+// "md5sum", "sha1sum", "sha256sum" or "sha512sum" is a GNU coreutils file with that hash,
+// and "bsd" or "tag" is a BSD tagged file with every hash.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "json":
+		return JSONFormat{}, nil
+	case "gnu":
+		return GNUFormat{}, nil
+	case "bsd", "tag":
+		return BSDFormat{}, nil
+	case "md5sum":
+		return GNUFormat{Algorithm: MD5}, nil
+	case "sha1sum":
+		return GNUFormat{Algorithm: SHA1}, nil
+	case "sha256sum":
+		return GNUFormat{Algorithm: SHA256}, nil
+	case "sha512sum":
+		return GNUFormat{Algorithm: SHA512}, nil
+	}
+	return nil, fmt.Errorf("Unknown manifest format %v, expected json, gnu, md5sum, sha1sum, sha256sum, sha512sum or bsd", name)
+}
+
+// detectFormat looks at the start of a manifest file to guess what format it's in.
+// An empty file is JSON, so it's saved as a new `manifest.json` would be.
+func detectFormat(data []byte) Format {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] == '{' {
+		return JSONFormat{}
+	}
+	firstLine := string(trimmed)
+	if end := strings.IndexByte(firstLine, '\n'); end >= 0 {
+		firstLine = firstLine[:end]
+	}
+	if isBSDLine(strings.TrimSuffix(firstLine, "\r")) {
+		return BSDFormat{}
+	}
+	return GNUFormat{}
+}
+
+// decodeLines reads a text manifest one line at a time, skipping blank lines.
+// `parse` splits each line into the file name, the algorithm, and the hash.
+func decodeLines(r io.Reader, m *Manifest, parse func(line string) (fileName, algorithm, hash string, err error)) error {
+	if *m == nil {
+		*m = Manifest{}
+	}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fileName, algorithm, hash, err := parse(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		m.add(fileName, algorithm, hash)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+//go:build ignore
+
+// gen_norm generates norm_tables.go from the Unicode Character Database.
+//
+//	go run gen_norm.go -data UnicodeData.txt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	version = flag.String("version", unicode.Version, "Unicode version of the data, the same as the unicode package by default.")
+	data    = flag.String("data", "", "UnicodeData.txt file, if empty it is downloaded from unicode.org.")
+	output  = flag.String("output", "norm_tables.go", "File to write the tables to.")
+)
+
+func main() {
+	flag.Parse()
+	r, err := open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	decompositions := map[rune][]rune{}
+	classes := map[rune]int{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ";")
+		if len(fields) < 6 {
+			continue
+		}
+		code, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ccc, _ := strconv.Atoi(fields[3]); ccc != 0 {
+			classes[rune(code)] = ccc
+		}
+		// only canonical decompositions, compatibility ones start with a <tag>
+		if fields[5] == "" || strings.HasPrefix(fields[5], "<") {
+			continue
+		}
+		var d []rune
+		for _, hex := range strings.Fields(fields[5]) {
+			c, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				log.Fatal(err)
+			}
+			d = append(d, rune(c))
+		}
+		decompositions[rune(code)] = d
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by gen_norm.go from UnicodeData.txt %v; DO NOT EDIT.\n\n", *version)
+	fmt.Fprintf(b, "package manifest\n\n")
+	fmt.Fprintf(b, "// canonicalDecompositions maps a rune to its full canonical decomposition.\n")
+	fmt.Fprintf(b, "var canonicalDecompositions = map[rune]string{\n")
+	for _, code := range sortedRunes(decompositions) {
+		fmt.Fprintf(b, "\t0x%04X: %v,\n", code, strconv.QuoteToASCII(string(decompose(code, decompositions))))
+	}
+	fmt.Fprintf(b, "}\n\n")
+	fmt.Fprintf(b, "// combiningClasses maps a rune to its canonical combining class, any rune that isn't here is class 0.\n")
+	fmt.Fprintf(b, "var combiningClasses = map[rune]uint8{\n")
+	for _, code := range sortedRunes(classes) {
+		fmt.Fprintf(b, "\t0x%04X: %d,\n", code, classes[code])
+	}
+	fmt.Fprintf(b, "}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// open the data file, or download it
+func open() (io.ReadCloser, error) {
+	if *data != "" {
+		return os.Open(*data)
+	}
+	url := fmt.Sprintf("https://www.unicode.org/Public/%v/ucd/UnicodeData.txt", *version)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Couldn't download %v: %v", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// decompose a rune recursively, so the table has the full decomposition
+func decompose(code rune, decompositions map[rune][]rune) []rune {
+	d, ok := decompositions[code]
+	if !ok {
+		return []rune{code}
+	}
+	var full []rune
+	for _, c := range d {
+		full = append(full, decompose(c, decompositions)...)
+	}
+	return full
+}
+
+func sortedRunes[V any](m map[rune]V) []rune {
+	codes := make([]rune, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GNUFormat is the format of GNU coreutils `sha256sum`, `sha1sum` and `md5sum`, ex. `SHA256SUMS` files.
+// Each line is "<hex>  <path>" or "<hex> *<path>" for binary mode.
+// If the path has a backslash or newline in it, the line starts with a backslash and the path is escaped.
+type GNUFormat struct {
+	// Algorithm is the hash written by Encode, if it's empty then the algorithm with the longest hash is used.
+	// Decode figures out the algorithm from the length of each hash.
+	Algorithm string
+}
+
+// Decode reads every "<hex>  <path>" line into the manifest.
+func (f GNUFormat) Decode(r io.Reader, m *Manifest) error {
+	return decodeLines(r, m, f.parseLine)
+}
+
+// parseLine splits a line into the file name, the algorithm, and the hash
+func (f GNUFormat) parseLine(line string) (fileName, algorithm, hash string, err error) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+	space := strings.IndexByte(line, ' ')
+	if space < 0 || space+2 > len(line) || (line[space+1] != ' ' && line[space+1] != '*') {
+		return "", "", "", fmt.Errorf("expected \"<hash>  <file>\" but got %q", line)
+	}
+	hash = strings.ToLower(line[:space])
+	fileName = line[space+2:]
+	if escaped {
+		fileName = unescapeFileName(fileName)
+	}
+	algorithm, err = algorithmForHash(hash, f.Algorithm)
+	return cleanFileName(fileName), algorithm, hash, err
+}
+
+// Encode writes one "<hex>  <path>" line for each file, sorted by path.
+func (f GNUFormat) Encode(w io.Writer, m *Manifest) error {
+	bw := bufio.NewWriter(w)
+	for _, fileName := range m.FileNames() {
+		sum := (*m)[fileName]
+		if len(sum.Algorithms()) == 0 {
+			// links and folders can't be written in this format
+			continue
+		}
+		algorithm := f.Algorithm
+		if algorithm == "" {
+			algorithm = sum.longestAlgorithm()
+		}
+		hash, ok := sum[algorithm]
+		if !ok {
+			return fmt.Errorf("%v doesn't have a %v hash", fileName, algorithm)
+		}
+		prefix := ""
+		if escapedName := escapeFileName(fileName); escapedName != fileName {
+			prefix = "\\"
+			fileName = escapedName
+		}
+		fmt.Fprintf(bw, "%v%v  %v\n", prefix, hash, fileName)
+	}
+	return bw.Flush()
+}
+
+// algorithmForHash returns the first registered algorithm that has hashes as long as this one.
+// If `want` is not empty then it is used, as long as the hash is the right length.
+func algorithmForHash(hash, want string) (string, error) {
+	for _, r := range hash {
+		if !hexRune(r) {
+			return "", fmt.Errorf("%q is not a hex hash", hash)
+		}
+	}
+	if want != "" {
+		h, ok := Lookup(want)
+		if !ok {
+			return "", fmt.Errorf("Unknown hash algorithm %v", want)
+		}
+		if h.HexLen() != len(hash) {
+			return "", fmt.Errorf("%v is not a %v hash", hash, h.Name())
+		}
+		return h.Name(), nil
+	}
+	for _, name := range Algorithms() {
+		if h, _ := Lookup(name); h.HexLen() == len(hash) {
+			return h.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("No hash algorithm has %d characters like %v", len(hash), hash)
+}
+
+// escapeFileName escapes backslashes and newlines the same way as coreutils
+func escapeFileName(fileName string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(fileName)
+}
+
+// unescapeFileName undoes escapeFileName
+func unescapeFileName(fileName string) string {
+	var b strings.Builder
+	for i := 0; i < len(fileName); i++ {
+		if fileName[i] == '\\' && i+1 < len(fileName) {
+			i++
+			switch fileName[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(fileName[i])
+			}
+			continue
+		}
+		b.WriteByte(fileName[i])
+	}
+	return b.String()
+}
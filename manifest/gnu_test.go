@@ -0,0 +1,102 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_GNUFormat_Decode(t *testing.T) {
+	// GIVEN a sha256sum file with text, binary, and escaped file names
+	text := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.txt\n" +
+		"3E23E8160039594A33894F6564E1B1348BBD7A0088D42C4ACB73EEAED59C009D *./b.txt\n" +
+		"\\0cc175b9c0f1b6a831c399e269772661  dir\\\\new\\nline.txt\n" +
+		"\n"
+
+	// WHEN it is decoded
+	m := Manifest{}
+	if err := (GNUFormat{}).Decode(strings.NewReader(text), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN each file should have a hash of the right algorithm
+	if m["a.txt"][SHA256] != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+		t.Errorf("a.txt SHA256 wrong %v", m["a.txt"])
+	}
+	if m["b.txt"][SHA256] != "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d" {
+		t.Errorf("b.txt should be binary mode, and lower case %v", m)
+	}
+	if m["dir\\new\nline.txt"][MD5] != "0cc175b9c0f1b6a831c399e269772661" {
+		t.Errorf("The escaped file name wasn't unescaped %v", m)
+	}
+	if len(m) != 3 {
+		t.Errorf("Expected 3 files %v", m)
+	}
+}
+
+func Test_GNUFormat_Decode_BadLine(t *testing.T) {
+	// GIVEN lines that aren't in the GNU format
+	for _, line := range []string{"a.txt", "0cc175b9c0f1b6a831c399e269772661 a.txt", "0cc175b9c0  a.txt", "xyz  a.txt"} {
+		// WHEN it is decoded
+		m := Manifest{}
+		err := (GNUFormat{}).Decode(strings.NewReader(line), &m)
+
+		// THEN it should error
+		if err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
+
+func Test_GNUFormat_Encode(t *testing.T) {
+	// GIVEN a manifest with every algorithm
+	m := Manifest{
+		"b.txt":   Sum{MD5: "92eb5ffee6ae2fec3ad71c777531578f", SHA256: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"},
+		"a\\.txt": Sum{MD5: "0cc175b9c0f1b6a831c399e269772661", SHA256: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+	}
+
+	// WHEN it is encoded as md5sum
+	buffer := &bytes.Buffer{}
+	if err := (GNUFormat{Algorithm: MD5}).Encode(buffer, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN it should be sorted, and escaped
+	expected := "\\0cc175b9c0f1b6a831c399e269772661  a\\\\.txt\n92eb5ffee6ae2fec3ad71c777531578f  b.txt\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected %q got %q", expected, buffer.String())
+	}
+
+	// WHEN it is encoded with the longest hash, and decoded again
+	buffer.Reset()
+	if err := (GNUFormat{}).Encode(buffer, &m); err != nil {
+		t.Fatal(err)
+	}
+	decoded := Manifest{}
+	if err := (GNUFormat{}).Decode(buffer, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN the SHA256 hashes should be the same
+	for fileName, sum := range m {
+		if decoded[fileName][SHA256] != sum[SHA256] {
+			t.Errorf("%v expected %v got %v", fileName, sum, decoded[fileName])
+		}
+	}
+}
+
+func Test_Manifest_Load_GNU(t *testing.T) {
+	// GIVEN a sha256sum file
+	// WHEN it is loaded as a manifest
+	m := Manifest{}
+	if err := m.Load("../test_data/other_manifests", "sha256sums.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN the format should be detected
+	if m["b.txt"][SHA256] != "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d" {
+		t.Errorf("b.txt SHA256 wrong %v", m)
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+//go:build !unix
+
+package manifest
+
+import "os"
+
+// inode is not available, so only the size and modification time are used
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
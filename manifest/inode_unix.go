@@ -0,0 +1,18 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+//go:build unix
+
+package manifest
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode returns the inode number of the file
+func inode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -3,41 +3,64 @@
 package manifest
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 )
 
 // Manifest is a collection of files and their hashed sums.
+// The file names are relative to the folder, and always use forward slashes.
 type Manifest map[string]Sum
 
 // Load the manifest file located in dirName.
+// The format is detected from the contents, either the default JSON, a GNU `sha256sum` style file, or a BSD tagged file.
 func (m *Manifest) Load(dirName, manifestName string) error {
+	_, err := m.LoadFormat(dirName, manifestName)
+	return err
+}
+
+// LoadFormat loads the manifest file like Load, and returns the format it was in so it can be saved the same way.
+// File names with backslashes from manifests made on Windows by older versions are changed to forward slashes.
+// A GNU or BSD file that only has one algorithm is returned with that Algorithm, so a `SHA256SUMS` file stays `sha256sum`.
+func (m *Manifest) LoadFormat(dirName, manifestName string) (Format, error) {
 	filename := path.Join(dirName, manifestName)
-	file, err := os.Open(filename)
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("Couldn't open manifest %v: %v", filename, err)
+		return nil, fmt.Errorf("Couldn't open manifest %v: %v", filename, err)
 	}
-	defer file.Close()
-	dec := json.NewDecoder(file)
-	if err = dec.Decode(m); err != nil {
-		return fmt.Errorf("Couldn't understand manifest file format %v: %v", filename, err)
+	format := detectFormat(data)
+	if err = format.Decode(bytes.NewReader(data), m); err != nil {
+		return nil, fmt.Errorf("Couldn't understand manifest file format %v: %v", filename, err)
 	}
-	return nil
+	m.migrateLegacyFileNames()
+	switch f := format.(type) {
+	case GNUFormat:
+		f.Algorithm = m.onlyAlgorithm()
+		format = f
+	case BSDFormat:
+		f.Algorithm = m.onlyAlgorithm()
+		format = f
+	}
+	return format, nil
 }
 
 // Save the list of hashes to the manifest file in dirName.
 func (m *Manifest) Save(dirName string, manifestName string) error {
+	return m.SaveFormat(dirName, manifestName, JSONFormat{})
+}
+
+// SaveFormat saves the list of hashes to the manifest file in dirName, in a specific format.
+func (m *Manifest) SaveFormat(dirName string, manifestName string, format Format) error {
 	filename := path.Join(dirName, manifestName)
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("Couldn't create manifest file %v: %v", filename, err)
 	}
 	defer file.Close()
-	enc := json.NewEncoder(file)
-	enc.SetIndent("", "\t")
-	if err = enc.Encode(m); err != nil {
+	if err = format.Encode(file, m); err != nil {
 		return err
 	}
 	return nil
@@ -55,3 +78,37 @@ func (m *Manifest) Verify(fileName string, sum Sum) error {
 	}
 	return nil
 }
+
+// add one hash to the file's sum
+func (m *Manifest) add(fileName, algorithm, hash string) {
+	sum, ok := (*m)[fileName]
+	if !ok {
+		sum = Sum{}
+		(*m)[fileName] = sum
+	}
+	sum[algorithm] = hash
+}
+
+// onlyAlgorithm returns the algorithm if every file has only that one, or empty if there is more than one
+func (m *Manifest) onlyAlgorithm() string {
+	only := ""
+	for _, sum := range *m {
+		for _, name := range sum.Algorithms() {
+			if only != "" && only != name {
+				return ""
+			}
+			only = name
+		}
+	}
+	return only
+}
+
+// FileNames returns every file name in the manifest, sorted.
+func (m *Manifest) FileNames() []string {
+	names := make([]string, 0, len(*m))
+	for name := range *m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
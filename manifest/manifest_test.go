@@ -4,7 +4,6 @@ package manifest
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -23,10 +22,13 @@ func Test_Manifest_Load(t *testing.T) {
 	// THEN it should contain the "a.txt" and "b.txt" files
 	expected := []string{
 		"a.txt", "b.txt",
-		fmt.Sprintf("%s%c%s", "bad_manifests", os.PathSeparator, "bad_b.json"),
-		fmt.Sprintf("%s%c%s", "bad_manifests", os.PathSeparator, "powershell.extra.md5.txt"),
-		fmt.Sprintf("%s%c%s", "other_manifests", os.PathSeparator, "powershell.md5.txt"),
-		fmt.Sprintf("%s%c%s", "other_manifests", os.PathSeparator, "powershell.sha1.txt"),
+		"bad_manifests/bad_b.json",
+		"bad_manifests/powershell.extra.md5.txt",
+		"other_manifests/legacy_windows.json",
+		"other_manifests/powershell.md5.txt",
+		"other_manifests/powershell.sha1.txt",
+		"other_manifests/sha256sums.txt",
+		"other_manifests/tagged.txt",
 	}
 	count := 0
 	for k := range m {
@@ -47,6 +49,78 @@ func Test_Manifest_Load(t *testing.T) {
 	}
 }
 
+func Test_Manifest_Load_LegacyWindows(t *testing.T) {
+	// GIVEN a manifest made on Windows with backslashes in the file names
+	m := Manifest{}
+
+	// WHEN it is loaded
+	if err := m.Load("../test_data/other_manifests", "legacy_windows.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN the file names should use forward slashes
+	if _, ok := m["other_manifests/sha256sums.txt"]; !ok {
+		t.Errorf("The file name should have been migrated %v", m)
+	}
+	if _, ok := m["other_manifests\\sha256sums.txt"]; ok {
+		t.Errorf("The backslash file name should be gone %v", m)
+	}
+	if len(m) != 2 {
+		t.Errorf("Expected 2 files %v", m)
+	}
+}
+
+func Test_Manifest_Load_BackslashCollision(t *testing.T) {
+	// GIVEN a manifest with a Unix file name that has a backslash, and a file in a sub folder with the same legacy name
+	dirName := t.TempDir()
+	saved := Manifest{
+		"a\\b.txt": Sum{MD5: "9dd4e461268c8034f5c8564e155c67a6"},
+		"a/b.txt":  Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"},
+	}
+	if err := saved.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN it is loaded
+	m := Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN both files are kept with their own sums
+	for fileName, sum := range saved {
+		if m[fileName][MD5] != sum[MD5] {
+			t.Errorf("Expected %v for %v got %v", sum, fileName, m)
+		}
+	}
+}
+
+func Test_Index_Find_Backslash(t *testing.T) {
+	// GIVEN a manifest with a Unix file name that has a backslash, and a file in a sub folder with the same legacy name
+	m := Manifest{
+		"a\\b.txt": Sum{MD5: "9dd4e461268c8034f5c8564e155c67a6"},
+		"a/b.txt":  Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"},
+	}
+	index := m.Index(PathExact)
+
+	// THEN each name is found exactly
+	for _, fileName := range []string{"a\\b.txt", "a/b.txt"} {
+		if name, ok := index.Find(fileName); !ok || name != fileName {
+			t.Errorf("Expected %v got %v", fileName, name)
+		}
+	}
+
+	// GIVEN a manifest where the backslash name was migrated when it was loaded
+	m = Manifest{
+		"c/d.txt": Sum{MD5: "0cc175b9c0f1b6a831c399e269772661"},
+	}
+
+	// THEN the Unix file name with a backslash finds it
+	if name, ok := m.Index(PathExact).Find("c\\d.txt"); !ok || name != "c/d.txt" {
+		t.Errorf("Expected c/d.txt got %v", name)
+	}
+}
+
 func Test_Manifest_Load_FileError(t *testing.T) {
 	// GIVEN a file that doesn't exist
 	dirname := "test_data"
@@ -97,11 +171,11 @@ func Test_Manifest_Save(t *testing.T) {
 	}
 
 	for k, v := range expected {
-		if v.MD5 != actual[k].MD5 {
-			t.Errorf("%v MD5 value expected %v actual %v", k, v, actual[k].MD5)
+		if v[MD5] != actual[k][MD5] {
+			t.Errorf("%v MD5 value expected %v actual %v", k, v, actual[k][MD5])
 		}
-		if v.SHA1 != actual[k].SHA1 {
-			t.Errorf("%v SHA1 value expected %v actual %v", k, v, actual[k].SHA1)
+		if v[SHA1] != actual[k][SHA1] {
+			t.Errorf("%v SHA1 value expected %v actual %v", k, v, actual[k][SHA1])
 		}
 	}
 }
@@ -0,0 +1,77 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+//go:generate go run gen_norm.go
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Hangul syllables decompose with a formula instead of a table.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulTCount = 28
+	hangulNCount = 21 * hangulTCount
+	hangulSCount = 19 * hangulNCount
+)
+
+// canonicalForm returns the canonical decomposition of s (NFD).
+// Two names that are canonically equivalent, like the NFD names from macOS and the NFC names from Linux, have the same canonical form.
+func canonicalForm(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	runes := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		if d, ok := canonicalDecompositions[r]; ok {
+			runes = append(runes, []rune(d)...)
+		} else if r >= hangulSBase && r < hangulSBase+hangulSCount {
+			runes = append(runes, decomposeHangul(r)...)
+		} else {
+			runes = append(runes, r)
+		}
+	}
+	// put every run of combining marks in canonical order
+	for i := 0; i < len(runes); {
+		if combiningClasses[runes[i]] == 0 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && combiningClasses[runes[j]] != 0 {
+			j++
+		}
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool {
+			return combiningClasses[marks[a]] < combiningClasses[marks[b]]
+		})
+		i = j
+	}
+	return string(runes)
+}
+
+// decomposeHangul splits a Hangul syllable into its jamo
+func decomposeHangul(r rune) []rune {
+	s := r - hangulSBase
+	l := hangulLBase + s/hangulNCount
+	v := hangulVBase + (s%hangulNCount)/hangulTCount
+	if t := s % hangulTCount; t != 0 {
+		return []rune{l, v, hangulTBase + t}
+	}
+	return []rune{l, v}
+}
+
+// isASCII returns true if every byte is ASCII, which never needs to be normalized
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,12 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+//go:build !unix
+
+package manifest
+
+import "os"
+
+// owner is not available, so it is never recorded or checked
+func owner(info os.FileInfo) string {
+	return ""
+}
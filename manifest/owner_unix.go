@@ -0,0 +1,19 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+//go:build unix
+
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// owner returns the user and group id of the file, ex. "1000:1000"
+func owner(info os.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", st.Uid, st.Gid)
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"path"
+	"strings"
+)
+
+// File names in a manifest always use forward slashes, ex. "bad_manifests/bad_b.json",
+// so a manifest made on Windows can be verified on Linux, and the other way around.
+
+// cleanFileName removes the "./" that `sha256sum ./*` puts in front of file names
+func cleanFileName(fileName string) string {
+	return strings.TrimPrefix(path.Clean(fileName), "./")
+}
+
+// legacyFileName converts a file name from a manifest made on Windows by an older version, ex. "bad_manifests\\bad_b.json".
+func legacyFileName(fileName string) string {
+	return cleanFileName(strings.Replace(fileName, "\\", "/", -1))
+}
+
+// migrateLegacyFileNames changes any backslash file names to forward slashes.
+// A backslash is a legal file name character on Unix, so if the forward slash name is already in the manifest
+// the backslash name is a different file, and it's kept as it is.
+func (m *Manifest) migrateLegacyFileNames() {
+	for _, fileName := range m.FileNames() {
+		if !strings.Contains(fileName, "\\") {
+			continue
+		}
+		newName := legacyFileName(fileName)
+		if _, ok := (*m)[newName]; ok {
+			continue
+		}
+		(*m)[newName] = (*m)[fileName]
+		delete(*m, fileName)
+	}
+}
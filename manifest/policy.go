@@ -0,0 +1,89 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathPolicy is how a file name in the folder is matched to a file name in the manifest.
+type PathPolicy int
+
+const (
+	// PathExact only matches file names that are exactly the same.
+	PathExact PathPolicy = iota
+	// PathNFC matches file names that are canonically equivalent in Unicode, so a decomposed "é" from macOS matches a composed "é" from Linux.
+	// Both names are compared in their canonical decomposition, so it doesn't matter which form either one is in.
+	PathNFC
+	// PathCaseInsensitive matches file names that are canonically equivalent, ignoring upper and lower case.
+	PathCaseInsensitive
+)
+
+// ParsePathPolicy returns the PathPolicy for a name used on the command line: "exact", "nfc" or "case".
+func ParsePathPolicy(name string) (PathPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "exact":
+		return PathExact, nil
+	case "nfc":
+		return PathNFC, nil
+	case "case", "nocase", "case-insensitive":
+		return PathCaseInsensitive, nil
+	}
+	return PathExact, fmt.Errorf("Unknown path policy %v, expected exact, nfc or case", name)
+}
+
+func (p PathPolicy) String() string {
+	switch p {
+	case PathExact:
+		return "exact"
+	case PathNFC:
+		return "nfc"
+	case PathCaseInsensitive:
+		return "case"
+	}
+	return fmt.Sprintf("PathPolicy(%d)", int(p))
+}
+
+// Key returns the file name in a form where two names that should match are equal.
+func (p PathPolicy) Key(fileName string) string {
+	switch p {
+	case PathNFC:
+		return canonicalForm(fileName)
+	case PathCaseInsensitive:
+		return strings.ToLower(canonicalForm(fileName))
+	}
+	return fileName
+}
+
+// Index finds the file names in a manifest using a PathPolicy.
+type Index struct {
+	policy PathPolicy
+	names  map[string]string
+}
+
+// Index makes an Index to find file names in the manifest with the policy.
+// If two names in the manifest are the same under the policy, the first one sorted is found.
+func (m *Manifest) Index(policy PathPolicy) *Index {
+	index := &Index{
+		policy: policy,
+		names:  make(map[string]string, len(*m)),
+	}
+	for _, fileName := range m.FileNames() {
+		key := policy.Key(fileName)
+		if _, ok := index.names[key]; !ok {
+			index.names[key] = fileName
+		}
+	}
+	return index
+}
+
+// Find returns the file name in the manifest that matches the file name, using the policy.
+// A Unix file name with a backslash that isn't in the manifest finds the forward slash name it was migrated to when it was loaded.
+func (i *Index) Find(fileName string) (manifestName string, ok bool) {
+	if manifestName, ok = i.names[i.policy.Key(fileName)]; ok || !strings.Contains(fileName, "\\") {
+		return
+	}
+	manifestName, ok = i.names[i.policy.Key(legacyFileName(fileName))]
+	return
+}
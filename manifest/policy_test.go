@@ -0,0 +1,75 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package manifest
+
+import "testing"
+
+func Test_PathPolicy_Key(t *testing.T) {
+	// GIVEN names that are the same, but written differently
+	nfc := "caf\u00e9/\ud55c.txt"              // composed, like Linux
+	nfd := "cafe\u0301/\u1112\u1161\u11ab.txt" // decomposed, like macOS
+	upper := "CAFÉ/한.TXT"
+
+	// WHEN they are compared exactly
+	// THEN they are different
+	if PathExact.Key(nfc) == PathExact.Key(nfd) {
+		t.Error("Exact should not normalize")
+	}
+
+	// WHEN they are normalized
+	// THEN they are the same
+	if PathNFC.Key(nfc) != PathNFC.Key(nfd) {
+		t.Errorf("%q and %q should be the same", PathNFC.Key(nfc), PathNFC.Key(nfd))
+	}
+	if PathNFC.Key(nfc) == PathNFC.Key(upper) {
+		t.Error("NFC should not ignore case")
+	}
+
+	// WHEN they are compared ignoring case
+	// THEN they are the same
+	if PathCaseInsensitive.Key(nfc) != PathCaseInsensitive.Key(upper) {
+		t.Errorf("%q and %q should be the same", PathCaseInsensitive.Key(nfc), PathCaseInsensitive.Key(upper))
+	}
+}
+
+func Test_canonicalForm_Order(t *testing.T) {
+	// GIVEN combining marks in different orders, a dot below (220) and a circumflex (230)
+	a := "e\u0323\u0302" // e, dot below, circumflex
+	b := "e\u0302\u0323" // e, circumflex, dot below
+
+	// WHEN they are normalized
+	// THEN the marks are put in canonical order, and the composed \u1ec7 is the same too
+	if canonicalForm(a) != canonicalForm(b) || canonicalForm(a) != canonicalForm("\u1ec7") {
+		t.Errorf("%q %q %q should be the same", canonicalForm(a), canonicalForm(b), canonicalForm("\u1ec7"))
+	}
+}
+
+func Test_ParsePathPolicy(t *testing.T) {
+	for name, expected := range map[string]PathPolicy{"": PathExact, "exact": PathExact, "NFC": PathNFC, "case": PathCaseInsensitive} {
+		if p, err := ParsePathPolicy(name); err != nil || p != expected {
+			t.Errorf("%q expected %v got %v %v", name, expected, p, err)
+		}
+	}
+	if _, err := ParsePathPolicy("noexist"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func Test_Manifest_Index(t *testing.T) {
+	// GIVEN a manifest made on macOS
+	m := Manifest{"cafe\u0301.txt": Sum{MD5: "x"}}
+
+	// WHEN the Linux name is found with the NFC policy
+	name, ok := m.Index(PathNFC).Find("caf\u00e9.txt")
+
+	// THEN the macOS name is found
+	if !ok || name != "cafe\u0301.txt" {
+		t.Errorf("Expected to find the macOS name, got %q %v", name, ok)
+	}
+
+	// WHEN it is found with the exact policy
+	// THEN it isn't found
+	if _, ok := m.Index(PathExact).Find("caf\u00e9.txt"); ok {
+		t.Error("Exact should not find the macOS name")
+	}
+}
@@ -3,51 +3,144 @@
 package manifest
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"sort"
 	"strings"
 )
 
-// Sum is a collection of hash strings.
-type Sum struct {
-	MD5  string
-	SHA1 string
-}
+// Sum is a collection of hash strings, keyed by the algorithm name, ex. `sum[SHA256]`.
+// Any algorithm that is missing was not calculated, for example SHA256 and SHA512 are missing from older manifests.
+type Sum map[string]string
 
 // Calculate takes a full-path filename and calculates the hashes of that file.
-func (s *Sum) Calculate(fileName string) error {
-	sha1hash := sha1.New()
-	md5hash := md5.New()
+// The file is only read once, every algorithm is calculated from the same pass.
+// If no algorithms are given then the DefaultAlgorithms are used.
+func (s *Sum) Calculate(fileName string, algorithms ...string) error {
+	if len(algorithms) == 0 {
+		algorithms = DefaultAlgorithms
+	}
+	hashes := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, name := range algorithms {
+		h, ok := Lookup(name)
+		if !ok {
+			return fmt.Errorf("Unknown hash algorithm %v", name)
+		}
+		hh := h.New()
+		hashes[h.Name()] = hh
+		writers = append(writers, hh)
+	}
 	file, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 	buffer := make([]byte, 65536)
-	for {
-		count, err := file.Read(buffer)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-		sha1hash.Write(buffer[:count])
-		md5hash.Write(buffer[:count])
+	if _, err := io.CopyBuffer(io.MultiWriter(writers...), file, buffer); err != nil {
+		return err
+	}
+	*s = make(Sum, len(hashes))
+	for name, hh := range hashes {
+		(*s)[name] = fmt.Sprintf("%x", hh.Sum(nil))
 	}
-	s.SHA1 = fmt.Sprintf("%x", sha1hash.Sum(nil))
-	s.MD5 = fmt.Sprintf("%x", md5hash.Sum(nil))
 	return nil
 }
 
 // Verify compares one sum to another sum, and makes sure all the hashes that are available match.
-func (s *Sum) Verify(other Sum) error {
-	if strings.ToLower(s.MD5) != strings.ToLower(other.MD5) {
-		return fmt.Errorf("MD5 mismatch %v != %v", s.MD5, other.MD5)
+// Only the algorithms that both sums have are compared, if they have none in common it is an error.
+// Symbolic links are compared by their target instead, and folders only have to be folders.
+func (s Sum) Verify(other Sum) error {
+	if s.IsLink() || other.IsLink() {
+		return s.verifyLink(other)
 	}
-	if strings.ToLower(s.SHA1) != strings.ToLower(other.SHA1) {
-		return fmt.Errorf("SHA1 mismatch %v != %v", s.SHA1, other.SHA1)
+	if s.IsDir() || other.IsDir() {
+		return s.verifyDir(other)
+	}
+	compared := 0
+	for _, name := range s.Algorithms() {
+		theirs, ok := other[name]
+		if !ok || theirs == "" || s[name] == "" {
+			continue
+		}
+		compared++
+		if strings.ToLower(s[name]) != strings.ToLower(theirs) {
+			return fmt.Errorf("%v mismatch %v != %v", name, s[name], theirs)
+		}
+	}
+	if compared == 0 {
+		return errors.New("No hash algorithms in common to compare")
+	}
+	return nil
+}
+
+// verifyLink compares the targets of two symbolic links
+func (s Sum) verifyLink(other Sum) error {
+	switch {
+	case !s.IsLink():
+		return fmt.Errorf("Expected a file, found a link to %v", other[Link])
+	case !other.IsLink():
+		return fmt.Errorf("Expected a link to %v, found a file", s[Link])
+	case s[Link] != other[Link]:
+		return fmt.Errorf("%v mismatch %v != %v", Link, s[Link], other[Link])
 	}
 	return nil
 }
+
+// verifyDir makes sure both sums are folders
+func (s Sum) verifyDir(other Sum) error {
+	switch {
+	case !s.IsDir():
+		return errors.New("Expected a file, found a folder")
+	case !other.IsDir():
+		return errors.New("Expected a folder, found a file")
+	}
+	return nil
+}
+
+// Algorithms returns the names of the hashes in the sum.
+// Registered algorithms come first in the order they were registered, then any others alphabetically.
+func (s Sum) Algorithms() []string {
+	names := make([]string, 0, len(s))
+	registered := map[string]bool{}
+	for _, name := range Algorithms() {
+		if _, ok := s[name]; ok {
+			names = append(names, name)
+			registered[name] = true
+		}
+	}
+	var others []string
+	for name := range s {
+		if !registered[name] && !IsAttribute(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// String formats the sum like "md5:0cc1...\tsha1:86f7...", any attributes come after the hashes
+func (s Sum) String() string {
+	parts := make([]string, 0, len(s))
+	for _, name := range s.Algorithms() {
+		parts = append(parts, fmt.Sprintf("%v:%v", strings.ToLower(name), s[name]))
+	}
+	for _, name := range s.Attributes() {
+		parts = append(parts, fmt.Sprintf("%v:%v", name, s[name]))
+	}
+	return strings.Join(parts, "\t")
+}
+
+// longestAlgorithm is the algorithm in the sum with the longest hash, which is usually the strongest
+func (s Sum) longestAlgorithm() string {
+	longest := ""
+	for _, name := range s.Algorithms() {
+		if len(s[name]) > len(s[longest]) {
+			longest = name
+		}
+	}
+	return longest
+}
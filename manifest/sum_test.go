@@ -9,6 +9,8 @@ func Test_Sum_Calculate(t *testing.T) {
 	filename := "../test_data/a.txt"
 	md5 := "0cc175b9c0f1b6a831c399e269772661"
 	sha1 := "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8"
+	sha256 := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	sha512 := "1f40fc92da241694750979ee6cf582f2d5d7d28e18335de05abc54d0560e0f5302860c652bf08d560252aa5e74210546f369fbbbce8c12cfc7957b2652fe9a75"
 
 	// WHEN the sums are calculated
 	sum := Sum{}
@@ -17,12 +19,20 @@ func Test_Sum_Calculate(t *testing.T) {
 	}
 
 	// THEN the values should be expected
-	if md5 != sum.MD5 {
-		t.Errorf("Exected MD5 %v got %v", md5, sum.MD5)
+	if md5 != sum[MD5] {
+		t.Errorf("Exected MD5 %v got %v", md5, sum[MD5])
 	}
 
-	if sha1 != sum.SHA1 {
-		t.Errorf("Exected SHA1 %v got %v", md5, sum.SHA1)
+	if sha1 != sum[SHA1] {
+		t.Errorf("Exected SHA1 %v got %v", md5, sum[SHA1])
+	}
+
+	if sha256 != sum[SHA256] {
+		t.Errorf("Exected SHA256 %v got %v", sha256, sum[SHA256])
+	}
+
+	if sha512 != sum[SHA512] {
+		t.Errorf("Exected SHA512 %v got %v", sha512, sum[SHA512])
 	}
 }
 
@@ -80,3 +90,44 @@ func Test_Sum_Verify(t *testing.T) {
 		t.Errorf("Expecting failure with SHA1 but didn't error. %v", badSha1)
 	}
 }
+
+func Test_Sum_Verify_CommonAlgorithms(t *testing.T) {
+	// GIVEN a sum from an older manifest without SHA256 or SHA512
+	old := Sum{
+		MD5:  "md5",
+		SHA1: "sha1",
+	}
+
+	// WHEN it is tested against a sum with every algorithm
+	full := Sum{
+		MD5:    "MD5",
+		SHA1:   "SHA1",
+		SHA256: "sha256",
+		SHA512: "sha512",
+	}
+
+	// THEN only the algorithms in common are compared
+	if err := old.Verify(full); err != nil {
+		t.Errorf("Expected verify to work, but didn't. %v", err)
+	}
+
+	// WHEN the algorithms in common don't match
+	badSha256 := Sum{
+		SHA256: "x",
+	}
+
+	// THEN it should fail
+	if err := full.Verify(badSha256); err == nil {
+		t.Errorf("Expecting failure with SHA256 but didn't error. %v", badSha256)
+	}
+
+	// WHEN there are no algorithms in common
+	onlySha512 := Sum{
+		SHA512: "sha512",
+	}
+
+	// THEN it should fail
+	if err := old.Verify(onlySha512); err == nil {
+		t.Errorf("Expecting failure with nothing to compare but didn't error. %v", onlySha512)
+	}
+}
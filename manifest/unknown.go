@@ -21,6 +21,7 @@ type HashLocation struct {
 }
 
 // LoadUnknownHashes loads an unknown text file format, and look for strings that look like hashes.
+// A hash is any run of hex characters the length of a registered algorithm.
 func LoadUnknownHashes(filename string) (*UnknownHashes, error) {
 	lineNumber := 1
 	file, err := os.Open(filename)
@@ -48,16 +49,12 @@ func LoadUnknownHashes(filename string) (*UnknownHashes, error) {
 			}
 			continue
 		}
-		if md5 := MD5InString(line); md5 != nil {
-			lower := strings.ToLower(*md5)
-			hl := HashLocation{
-				LineNumber: lineNumber,
-				Line:       string(line),
+		for _, length := range hexLengths() {
+			hash := hexString(line, length)
+			if hash == nil {
+				continue
 			}
-			result.Set(lower, hl)
-		}
-		if sha1 := SHA1InString(line); sha1 != nil {
-			lower := strings.ToLower(*sha1)
+			lower := strings.ToLower(*hash)
 			hl := HashLocation{
 				LineNumber: lineNumber,
 				Line:       string(line),
@@ -86,13 +83,13 @@ func (u *UnknownHashes) Remove(hash string) {
 	delete(*u, hash)
 }
 
-// RemoveSum removes the hash from the list if it's in the Sum structure
+// RemoveSum removes every hash in the Sum from the list
 func (u *UnknownHashes) RemoveSum(sum Sum) {
-	if _, ok := u.Get(sum.MD5); ok {
-		u.Remove(sum.MD5)
-	}
-	if _, ok := u.Get(sum.SHA1); ok {
-		u.Remove(sum.SHA1)
+	for _, name := range sum.Algorithms() {
+		lower := strings.ToLower(sum[name])
+		if _, ok := u.Get(lower); ok {
+			u.Remove(lower)
+		}
 	}
 }
 
@@ -106,6 +103,30 @@ func SHA1InString(line []rune) *string {
 	return hexString(line, 40)
 }
 
+// SHA256InString returns the substring that looks like a SHA256 sum in a string
+func SHA256InString(line []rune) *string {
+	return hexString(line, 64)
+}
+
+// SHA512InString returns the substring that looks like a SHA512 sum in a string
+func SHA512InString(line []rune) *string {
+	return hexString(line, 128)
+}
+
+// the distinct hex lengths of every registered algorithm
+func hexLengths() []int {
+	var lengths []int
+	seen := map[int]bool{}
+	for _, name := range Algorithms() {
+		h, _ := Lookup(name)
+		if !seen[h.HexLen()] {
+			seen[h.HexLen()] = true
+			lengths = append(lengths, h.HexLen())
+		}
+	}
+	return lengths
+}
+
 // returns true if the rune is a valid hexadecimal character
 func hexRune(r rune) bool {
 	if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') {
@@ -361,3 +361,55 @@ func Test_UnknownHashes_RemoveSet_SHA1(t *testing.T) {
 		t.Errorf("%v should have been removed", testHash)
 	}
 }
+
+func Test_SHA256InString(t *testing.T) {
+	// GIVEN a line with something that looks like a sha256
+	valid := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	line := valid + "  a.txt"
+
+	// WHEN SHA256InString is called
+	// THEN it should return that sha256
+	if val := SHA256InString([]rune(line)); val == nil || *val != valid {
+		t.Errorf("Expecting to find the sha256 sum, found %v", val)
+	}
+
+	// THEN it should not be mistaken for a shorter hash
+	if val := SHA1InString([]rune(line)); val != nil {
+		t.Errorf("Should have found no sha1, found %v", *val)
+	}
+}
+
+func Test_SHA512InString(t *testing.T) {
+	// GIVEN a line with something that looks like a sha512
+	valid := "1f40fc92da241694750979ee6cf582f2d5d7d28e18335de05abc54d0560e0f5302860c652bf08d560252aa5e74210546f369fbbbce8c12cfc7957b2652fe9a75"
+	line := "SHA512 (a.txt) = " + valid
+
+	// WHEN SHA512InString is called
+	// THEN it should return that sha512
+	if val := SHA512InString([]rune(line)); val == nil || *val != valid {
+		t.Errorf("Expecting to find the sha512 sum, found %v", val)
+	}
+
+	// THEN it should not be mistaken for a sha256
+	if val := SHA256InString([]rune(line)); val != nil {
+		t.Errorf("Should have found no sha256, found %v", *val)
+	}
+}
+
+func Test_UnknownHashes_RemoveSet_SHA256(t *testing.T) {
+	// GIVEN a hash set with a sha256
+	testHash := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	u := UnknownHashes{}
+	u.Set(testHash, HashLocation{LineNumber: 1})
+
+	// WHEN a SHA256 hash is in a sum to be removed
+	sum := Sum{
+		SHA256: testHash,
+	}
+	u.RemoveSum(sum)
+
+	// THEN it should no longer be in the set
+	if _, ok := u.Get(testHash); ok {
+		t.Errorf("%v should have been removed", testHash)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_hashFolder_CheckMeta(t *testing.T) {
+	// GIVEN a manifest with the mode of an executable script
+	dirName := t.TempDir()
+	fileName := filepath.Join(dirName, "script.sh")
+	if err := ioutil.WriteFile(fileName, []byte("echo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(fileName, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.meta = []string{manifest.Mode}
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// WHEN the script loses its exec bit
+	if err := os.Chmod(fileName, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN it passes if the mode isn't checked
+	_, errorBuffer, h = makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	if err := h.HashFolder(dirName); err != nil {
+		t.Error("The mode should only be checked with -check-meta", err, errorBuffer)
+	}
+
+	// THEN it fails as a metadata change, not a content change
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.checkMeta = []string{manifest.Mode}
+	if err := h.HashFolder(dirName); err == nil {
+		t.Error("The changed mode should fail")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Metadata script.sh: mode mismatch 0755 != 0644") {
+		t.Errorf("Expected a mode mismatch: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "0 changed, 1 metadata") {
+		t.Errorf("Expected the metadata to be counted: %v", is)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"sort"
+	"strings"
+)
+
+// digestIndex finds the files in a manifest by their hashes, it's the reverse of the manifest
+type digestIndex struct {
+	manifest *manifest.Manifest
+	names    map[string][]string // "MD5:0cc1..." to the file names with that hash, sorted
+	claimed  map[string]bool     // file names that were already matched to a moved file
+}
+
+// newDigestIndex indexes every file in the manifest with hashes, except the ones in `skip`
+func newDigestIndex(m *manifest.Manifest, skip map[string]bool) *digestIndex {
+	index := &digestIndex{manifest: m, names: map[string][]string{}, claimed: map[string]bool{}}
+	for fileName, sum := range *m {
+		if skip[fileName] {
+			continue
+		}
+		for _, algorithm := range sum.Algorithms() {
+			key := digestKey(algorithm, sum[algorithm])
+			index.names[key] = append(index.names[key], fileName)
+		}
+	}
+	for _, names := range index.names {
+		sort.Strings(names)
+	}
+	return index
+}
+
+// digestKey is how a hash is found in the index
+func digestKey(algorithm, hash string) string {
+	return algorithm + ":" + strings.ToLower(hash)
+}
+
+// find returns the first file in the index with the same contents as the sum, that hasn't already been claimed
+func (index *digestIndex) find(sum manifest.Sum) (fileName string, ok bool) {
+	for _, algorithm := range sum.Algorithms() {
+		for _, name := range index.names[digestKey(algorithm, sum[algorithm])] {
+			if index.claimed[name] {
+				continue
+			}
+			if err := (*index.manifest)[name].Verify(sum); err == nil {
+				index.claimed[name] = true
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// verifyNewFiles checks every new file against the files in the oldManifest that weren't found,
+// a new file with the same contents as one of them was moved or renamed
+func (h *folderHasher) verifyNewFiles(oldManifest *manifest.Manifest, result *verifyResult) {
+	index := newDigestIndex(oldManifest, result.manifestNames())
+	for _, f := range result.Files {
+		if f.Status != statusNew {
+			continue
+		}
+		if oldName, ok := index.find(f.Actual); ok {
+			f.Status = statusMoved
+			f.MovedFrom = oldName
+			f.Expected = (*oldManifest)[oldName]
+			f.manifestName = oldName
+			f.Accepted = h.acceptMoves
+			if h.update {
+				continue
+			}
+			if f.Accepted {
+				h.infoLog.Printf("Moved %v from %v\n", f.FileName, oldName)
+			} else {
+				h.errorLog.Printf("Moved %v from %v\n", f.FileName, oldName)
+			}
+			continue
+		}
+		if h.update {
+			continue
+		}
+		kind := ""
+		if f.Actual.IsDir() {
+			kind = "folder "
+		}
+		if h.strict {
+			h.errorLog.Printf("New %v%v was not in %v\n", kind, f.FileName, h.manifestFileName)
+		} else if len(*oldManifest) > 0 {
+			h.infoLog.Printf("New %v%v was not in %v\n", kind, f.FileName, h.manifestFileName)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeMovedFolder makes a folder with a manifest, then moves sub/b.txt to other/renamed.txt
+// and replaces c.txt with d.txt that has different contents
+func makeMovedFolder(t *testing.T) string {
+	dirName := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a.txt": "a", "sub/b.txt": "b", "c.txt": "c"})
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5, manifest.SHA1}
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+	if err := os.Mkdir(filepath.Join(dirName, "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dirName, "sub", "b.txt"), filepath.Join(dirName, "other", "renamed.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dirName, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirName, "d.txt"), []byte("d"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dirName
+}
+
+func Test_hashFolder_Moved(t *testing.T) {
+	// GIVEN a folder where a file was moved, and another was replaced
+	dirName := makeMovedFolder(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// WHEN it is verified
+	err := h.HashFolder(dirName)
+
+	// THEN the moved file is reported with its old name, and fails like a missing file
+	if code := exitCode(err); code != exitMissing {
+		t.Errorf("Expected %d got %d: %v", exitMissing, code, err)
+	}
+	es := errorBuffer.String()
+	if !strings.Contains(es, "Moved other/renamed.txt from sub/b.txt") {
+		t.Errorf("Expected the file to be moved: %v", es)
+	}
+	if strings.Contains(es, "Missing sub/b.txt") {
+		t.Errorf("The moved file shouldn't be missing too: %v", es)
+	}
+
+	// THEN the file with different contents isn't moved
+	if !strings.Contains(es, "Missing c.txt") {
+		t.Errorf("The replaced file should be missing: %v", es)
+	}
+}
+
+func Test_hashFolder_AcceptMoves(t *testing.T) {
+	// GIVEN a folder where a file was moved, and another was replaced
+	dirName := makeMovedFolder(t)
+	if err := os.Remove(filepath.Join(dirName, "d.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirName, "c.txt"), []byte("c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.acceptMoves = true
+
+	// WHEN it is hashed accepting moves
+	err := h.HashFolder(dirName)
+
+	// THEN it passes, and the manifest has the new name
+	if err != nil {
+		t.Errorf("The move should be accepted: %v %v", err, errorBuffer)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "Moved other/renamed.txt from sub/b.txt") || !strings.Contains(is, "1 moved") {
+		t.Errorf("Expected the move to be logged: %v", is)
+	}
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["other/renamed.txt"]; !ok {
+		t.Errorf("The manifest should have the new name %v", m)
+	}
+}
+
+func Test_digestIndex_find(t *testing.T) {
+	// GIVEN a manifest with two files with the same contents
+	m := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"b.txt": manifest.Sum{manifest.MD5: "0CC175B9C0F1B6A831C399E269772661"},
+		"c.txt": manifest.Sum{manifest.MD5: "4a8a08f09d37b73795649038408b5f33"},
+	}
+	index := newDigestIndex(&m, map[string]bool{"c.txt": true})
+	sum := manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"}
+
+	// THEN each one is only found once
+	if name, ok := index.find(sum); !ok || name != "a.txt" {
+		t.Errorf("Expected a.txt got %v", name)
+	}
+	if name, ok := index.find(sum); !ok || name != "b.txt" {
+		t.Errorf("Expected b.txt got %v", name)
+	}
+	if name, ok := index.find(sum); ok {
+		t.Errorf("Expected nothing got %v", name)
+	}
+
+	// THEN skipped files aren't found
+	if name, ok := index.find(manifest.Sum{manifest.MD5: "4a8a08f09d37b73795649038408b5f33"}); ok {
+		t.Errorf("c.txt was skipped, but found %v", name)
+	}
+}
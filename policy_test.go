@@ -0,0 +1,108 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func Test_hashFolder_PathPolicy(t *testing.T) {
+	// GIVEN a file with an NFC name, and a manifest made on macOS with the NFD name
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "caf\u00e9.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := manifest.Manifest{"cafe\u0301.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"}}
+	if err := m.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// WHEN it is verified with the exact policy
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// THEN the file doesn't match its name, so it was moved from the NFD name
+	if err := h.HashFolder(dirName); err == nil {
+		t.Error("The NFD name should not match with the exact policy")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Moved caf\u00e9.txt from cafe\u0301.txt") {
+		t.Errorf("Expected a moved file: %v", es)
+	}
+
+	// WHEN it is verified with the nfc policy
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.pathPolicy = manifest.PathNFC
+
+	// THEN the file is found
+	if err := h.HashFolder(dirName); err != nil {
+		t.Errorf("The file should have been found with the nfc policy: %v %v", err, errorBuffer)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "1 ok") {
+		t.Errorf("Expected the file to be ok: %v", is)
+	}
+}
+
+func Test_hashFolder_PathPolicy_Collision(t *testing.T) {
+	// GIVEN two files that are only different by case
+	dirName := t.TempDir()
+	for _, name := range []string{"A.txt", "a.txt"} {
+		if err := ioutil.WriteFile(path.Join(dirName, name), []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// WHEN the folder is hashed with the case-insensitive policy
+	infoBuffer, _, h := makeTestFolderHasher("", "")
+	h.pathPolicy = manifest.PathCaseInsensitive
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err)
+	}
+
+	// THEN there should be a warning that they collide
+	if is := infoBuffer.String(); !strings.Contains(is, "Warning: A.txt and a.txt") {
+		t.Errorf("Expected a collision warning: %v", is)
+	}
+}
+
+func Test_hashFolder_PathPolicy_Quick(t *testing.T) {
+	// GIVEN a file with an NFC name, a manifest with the NFD name, and a cache outside of the folder
+	dirName := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dirName, "caf\u00e9.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := manifest.Manifest{"cafe\u0301.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"}}
+	if err := m.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	cacheFileName := path.Join(t.TempDir(), "manifest.cache")
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.full = true
+	h.cacheFileName = cacheFileName
+	h.algorithms = []string{manifest.MD5}
+	h.pathPolicy = manifest.PathNFC
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// WHEN it is verified in quick mode with the nfc policy
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.quick = true
+	h.cacheFileName = cacheFileName
+	h.algorithms = []string{manifest.MD5}
+	h.pathPolicy = manifest.PathNFC
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+
+	// THEN the file is found in the cache
+	if is := infoBuffer.String(); !strings.Contains(is, "(1 unchanged in cache)") {
+		t.Errorf("Expected the file to be unchanged in the cache: %v", is)
+	}
+}
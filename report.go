@@ -0,0 +1,175 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io"
+	"os"
+	"strings"
+)
+
+// reportFormat is how the results are written for other programs to read.
+type reportFormat int
+
+const (
+	reportText  reportFormat = iota // only the log lines, there is no report
+	reportJSON                      // a JSON document with every file
+	reportJUnit                     // a JUnit XML document where every file is a test case
+	reportTAP                       // a Test Anything Protocol line for every file
+)
+
+// parseReportFormat returns the reportFormat for a name used on the command line: "text", "json", "junit" or "tap".
+func parseReportFormat(name string) (reportFormat, error) {
+	switch strings.ToLower(name) {
+	case "", "text":
+		return reportText, nil
+	case "json":
+		return reportJSON, nil
+	case "junit", "xml":
+		return reportJUnit, nil
+	case "tap":
+		return reportTAP, nil
+	}
+	return reportText, fmt.Errorf("Unknown report format %v, expected text, json, junit or tap", name)
+}
+
+func (f reportFormat) String() string {
+	switch f {
+	case reportText:
+		return "text"
+	case reportJSON:
+		return "json"
+	case reportJUnit:
+		return "junit"
+	case reportTAP:
+		return "tap"
+	}
+	return fmt.Sprintf("reportFormat(%d)", int(f))
+}
+
+// writeReport writes the result to the reportFileName or reportOutput in the report format
+func (h *folderHasher) writeReport(dirName string, result *verifyResult) error {
+	if h.report == reportText {
+		return nil
+	}
+	w := h.reportOutput
+	if h.reportFileName != "" {
+		file, err := os.Create(h.reportFileName)
+		if err != nil {
+			return fmt.Errorf("Couldn't create the report %v: %v", h.reportFileName, err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if w == nil {
+		return nil
+	}
+	var err error
+	switch h.report {
+	case reportJSON:
+		err = writeJSONReport(w, h.newJSONReport(dirName, result))
+	case reportJUnit:
+		err = writeJUnitReport(w, h.newJUnitReport(dirName, result))
+	case reportTAP:
+		err = h.writeTAPReport(w, dirName, result)
+	}
+	if err != nil {
+		return fmt.Errorf("Couldn't write the %v report: %v", h.report, err)
+	}
+	return nil
+}
+
+// resultMessage describes why a file isn't ok, like the log does
+func resultMessage(f *fileResult, manifestFileName string) string {
+	switch f.Status {
+	case statusNew:
+		return fmt.Sprintf("New %v was not in %v", f.FileName, manifestFileName)
+	case statusMissing:
+		return fmt.Sprintf("Missing %v was in %v, but not found in dir", f.FileName, manifestFileName)
+	case statusMoved:
+		return fmt.Sprintf("Moved %v from %v", f.FileName, f.MovedFrom)
+	}
+	if f.Err != nil {
+		return f.Err.Error()
+	}
+	return f.Status.String()
+}
+
+// jsonReport is the document written by -report json
+type jsonReport struct {
+	Root     string              `json:"root"`
+	Manifest string              `json:"manifest,omitempty"`
+	Unknown  string              `json:"unknownFile,omitempty"`
+	Passed   bool                `json:"passed"`
+	Summary  map[string]int      `json:"summary"`
+	Files    []jsonFileReport    `json:"files"`
+	Hashes   []jsonUnknownReport `json:"unknown"`
+}
+
+// jsonFileReport is one file in the jsonReport
+type jsonFileReport struct {
+	Name      string       `json:"name"`
+	Status    string       `json:"status"`
+	Expected  manifest.Sum `json:"expected,omitempty"`
+	Actual    manifest.Sum `json:"actual,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Cached    bool         `json:"cached,omitempty"`
+	MovedFrom string       `json:"movedFrom,omitempty"`
+	Accepted  bool         `json:"accepted,omitempty"`
+}
+
+// jsonUnknownReport is a hash from the -unknown file that wasn't found
+type jsonUnknownReport struct {
+	Hash       string `json:"hash"`
+	LineNumber int    `json:"lineNumber"`
+	Line       string `json:"line"`
+}
+
+// newJSONReport converts the result into the document written by -report json
+func (h *folderHasher) newJSONReport(dirName string, result *verifyResult) *jsonReport {
+	report := &jsonReport{
+		Root:     dirName,
+		Manifest: h.manifestFileName,
+		Unknown:  h.unknownFileName,
+		Passed:   !result.failed(h.strict),
+		Summary:  map[string]int{"total": len(result.Files), "cached": result.cached(), "unknown": len(result.Unknown)},
+		Files:    make([]jsonFileReport, 0, len(result.Files)),
+		Hashes:   make([]jsonUnknownReport, 0, len(result.Unknown)),
+	}
+	for _, status := range fileStatuses {
+		report.Summary[status.String()] = result.count(status)
+	}
+	for _, f := range result.Files {
+		fr := jsonFileReport{
+			Name:      f.FileName,
+			Status:    f.Status.String(),
+			Expected:  f.Expected,
+			Actual:    f.Actual,
+			Cached:    f.Cached,
+			MovedFrom: f.MovedFrom,
+			Accepted:  f.Accepted,
+		}
+		if f.Err != nil {
+			fr.Error = f.Err.Error()
+		}
+		report.Files = append(report.Files, fr)
+	}
+	for _, u := range result.Unknown {
+		report.Hashes = append(report.Hashes, jsonUnknownReport{
+			Hash:       u.Hash,
+			LineNumber: u.Location.LineNumber,
+			Line:       u.Location.Line,
+		})
+	}
+	return report
+}
+
+// writeJSONReport encodes the report with tabs, like the manifest
+func writeJSONReport(w io.Writer, report *jsonReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(report)
+}
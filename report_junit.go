@@ -0,0 +1,102 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// junitTestSuites is the document written by -report junit, every file is a test case
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is the manifest, or the -unknown file
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one file, or one hash from the -unknown file
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure is why a test case failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// add a test case to the suite
+func (s *junitTestSuite) add(c junitTestCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+}
+
+// newJUnitReport converts the result into the document written by -report junit
+func (h *folderHasher) newJUnitReport(dirName string, result *verifyResult) *junitTestSuites {
+	files := junitTestSuite{Name: h.manifestFileName}
+	for _, f := range result.Files {
+		c := junitTestCase{Name: f.FileName, ClassName: h.manifestFileName}
+		if f.failed(h.strict) {
+			c.Failure = &junitFailure{
+				Message: resultMessage(f, h.manifestFileName),
+				Type:    f.Status.String(),
+				Text:    fmt.Sprintf("expected: %v\nactual: %v", f.Expected, f.Actual),
+			}
+		} else if f.Status != statusOK {
+			c.SystemOut = resultMessage(f, h.manifestFileName)
+		}
+		files.add(c)
+	}
+	suites := &junitTestSuites{Suites: []junitTestSuite{files}}
+	if h.unknownFileName != "" {
+		unknown := junitTestSuite{Name: h.unknownFileName}
+		for _, u := range result.Unknown {
+			unknown.add(junitTestCase{
+				Name:      fmt.Sprintf("line %d", u.Location.LineNumber),
+				ClassName: h.unknownFileName,
+				Failure: &junitFailure{
+					Message: fmt.Sprintf("Hash %v was in %v line %d, but not found in dir %v", u.Hash, h.unknownFileName, u.Location.LineNumber, dirName),
+					Type:    "unknown",
+					Text:    u.Location.Line,
+				},
+			})
+		}
+		suites.Suites = append(suites.Suites, unknown)
+	}
+	for _, s := range suites.Suites {
+		suites.Tests += s.Tests
+		suites.Failures += s.Failures
+	}
+	return suites
+}
+
+// writeJUnitReport encodes the report with tabs, like the manifest
+func writeJUnitReport(w io.Writer, report *junitTestSuites) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
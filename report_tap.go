@@ -0,0 +1,69 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeTAPReport writes a Test Anything Protocol line for every file, and every -unknown hash that wasn't found.
+// Anything that failed has YAML diagnostics with the expected and actual digests.
+func (h *folderHasher) writeTAPReport(w io.Writer, dirName string, result *verifyResult) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "TAP version 13\n")
+	fmt.Fprintf(bw, "1..%d\n", len(result.Files)+len(result.Unknown))
+	test := 0
+	for _, f := range result.Files {
+		test++
+		if !f.failed(h.strict) {
+			directive := ""
+			if f.Status != statusOK {
+				directive = " # " + f.Status.String()
+			}
+			fmt.Fprintf(bw, "ok %d - %v%v\n", test, tapDescription(f.FileName), directive)
+			continue
+		}
+		fmt.Fprintf(bw, "not ok %d - %v\n", test, tapDescription(f.FileName))
+		fmt.Fprintf(bw, "  ---\n")
+		fmt.Fprintf(bw, "  status: %v\n", f.Status)
+		fmt.Fprintf(bw, "  message: %v\n", strconv.Quote(resultMessage(f, h.manifestFileName)))
+		writeTAPSum(bw, "expected", f.Expected)
+		writeTAPSum(bw, "actual", f.Actual)
+		fmt.Fprintf(bw, "  ...\n")
+	}
+	for _, u := range result.Unknown {
+		test++
+		fmt.Fprintf(bw, "not ok %d - %v line %d\n", test, tapDescription(h.unknownFileName), u.Location.LineNumber)
+		fmt.Fprintf(bw, "  ---\n")
+		fmt.Fprintf(bw, "  status: unknown\n")
+		fmt.Fprintf(bw, "  message: %v\n", strconv.Quote(fmt.Sprintf("Hash %v was in %v line %d, but not found in dir %v", u.Hash, h.unknownFileName, u.Location.LineNumber, dirName)))
+		fmt.Fprintf(bw, "  hash: %v\n", u.Hash)
+		fmt.Fprintf(bw, "  line: %v\n", strconv.Quote(u.Location.Line))
+		fmt.Fprintf(bw, "  ...\n")
+	}
+	return bw.Flush()
+}
+
+// writeTAPSum writes a sum as a YAML map of algorithm to digest, or nothing if there isn't a sum
+func writeTAPSum(w io.Writer, name string, sum manifest.Sum) {
+	if len(sum) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  %v:\n", name)
+	for _, algorithm := range sum.Algorithms() {
+		fmt.Fprintf(w, "    %v: %v\n", algorithm, sum[algorithm])
+	}
+	for _, attribute := range sum.Attributes() {
+		fmt.Fprintf(w, "    %v: %v\n", attribute, strconv.Quote(sum[attribute]))
+	}
+}
+
+// tapDescription escapes a "#" so a file name can't be read as a directive
+func tapDescription(name string) string {
+	return strings.Replace(name, "#", "\\#", -1)
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeReportFolder makes a folder where a.txt is ok, b.txt changed, c.txt is new and d.txt is missing,
+// and an unknown file with a hash on line 2 that isn't in the folder
+func makeReportFolder(t *testing.T) (dirName, unknownFileName string) {
+	dirName = t.TempDir()
+	for name, contents := range map[string]string{"a.txt": "a", "b.txt": "changed", "c.txt": "c"} {
+		if err := ioutil.WriteFile(filepath.Join(dirName, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := manifest.Manifest{
+		"a.txt": manifest.Sum{manifest.MD5: "0cc175b9c0f1b6a831c399e269772661"},
+		"b.txt": manifest.Sum{manifest.MD5: "92eb5ffee6ae2fec3ad71c777531578f"},
+		"d.txt": manifest.Sum{manifest.MD5: "8277e0910d750195b448797616e091ad"},
+	}
+	if err := m.Save(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	unknownFileName = filepath.Join(t.TempDir(), "unknown.txt")
+	unknown := "0cc175b9c0f1b6a831c399e269772661 a.txt\nd41d8cd98f00b204e9800998ecf8427e empty.txt\n"
+	if err := ioutil.WriteFile(unknownFileName, []byte(unknown), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func Test_hashFolder_ReportJSON(t *testing.T) {
+	// GIVEN a folder with every kind of result
+	dirName, unknownFileName := makeReportFolder(t)
+	_, _, h := makeTestFolderHasher("manifest.json", unknownFileName)
+	h.algorithms = []string{manifest.MD5}
+	h.verifyOnly = true
+	report := &bytes.Buffer{}
+	h.report = reportJSON
+	h.reportOutput = report
+
+	// WHEN the folder is verified with a JSON report
+	if err := h.HashFolder(dirName); err == nil {
+		t.Error("The folder should fail")
+	}
+
+	// THEN the report has every file
+	var r jsonReport
+	if err := json.Unmarshal(report.Bytes(), &r); err != nil {
+		t.Fatal(err, report)
+	}
+	if r.Passed {
+		t.Error("The report should not pass")
+	}
+	statuses := map[string]string{}
+	for _, f := range r.Files {
+		statuses[f.Name] = f.Status
+	}
+	expected := map[string]string{"a.txt": "ok", "b.txt": "changed", "c.txt": "new", "d.txt": "missing"}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("Expected %v to be %v got %v", name, status, statuses[name])
+		}
+	}
+	for _, f := range r.Files {
+		if f.Name == "b.txt" && (f.Expected[manifest.MD5] != "92eb5ffee6ae2fec3ad71c777531578f" || f.Actual[manifest.MD5] == "" || f.Error == "") {
+			t.Errorf("The changed file should have both digests and an error %v", f)
+		}
+	}
+
+	// THEN the unknown hash has its line
+	if len(r.Hashes) != 1 || r.Hashes[0].Hash != "d41d8cd98f00b204e9800998ecf8427e" || r.Hashes[0].LineNumber != 2 {
+		t.Errorf("Expected the empty file's hash on line 2 got %v", r.Hashes)
+	}
+
+	// THEN the summary has the totals
+	if r.Summary["total"] != 4 || r.Summary["ok"] != 1 || r.Summary["missing"] != 1 || r.Summary["unknown"] != 1 {
+		t.Errorf("Unexpected summary %v", r.Summary)
+	}
+}
+
+func Test_hashFolder_ReportJUnit(t *testing.T) {
+	// GIVEN a folder with every kind of result
+	dirName, unknownFileName := makeReportFolder(t)
+	_, _, h := makeTestFolderHasher("manifest.json", unknownFileName)
+	h.algorithms = []string{manifest.MD5}
+	h.verifyOnly = true
+	report := &bytes.Buffer{}
+	h.report = reportJUnit
+	h.reportOutput = report
+
+	// WHEN the folder is verified with a JUnit report
+	if err := h.HashFolder(dirName); err == nil {
+		t.Error("The folder should fail")
+	}
+
+	// THEN every file is a test case, and the changed and missing files failed
+	var r junitTestSuites
+	if err := xml.Unmarshal(report.Bytes(), &r); err != nil {
+		t.Fatal(err, report)
+	}
+	if r.Tests != 5 || r.Failures != 3 || len(r.Suites) != 2 {
+		t.Fatalf("Expected 5 tests with 3 failures in 2 suites got %v", report)
+	}
+	failures := map[string]string{}
+	for _, c := range r.Suites[0].Cases {
+		if c.Failure != nil {
+			failures[c.Name] = c.Failure.Type
+		}
+	}
+	if len(failures) != 2 || failures["b.txt"] != "changed" || failures["d.txt"] != "missing" {
+		t.Errorf("Expected b.txt changed and d.txt missing got %v", failures)
+	}
+
+	// THEN the unknown hash failed with its line number
+	if c := r.Suites[1].Cases; len(c) != 1 || c[0].Name != "line 2" || c[0].Failure == nil {
+		t.Errorf("Expected the unknown hash on line 2 to fail %v", c)
+	}
+}
+
+func Test_hashFolder_ReportTAP(t *testing.T) {
+	// GIVEN a folder with every kind of result
+	dirName, unknownFileName := makeReportFolder(t)
+	_, _, h := makeTestFolderHasher("manifest.json", unknownFileName)
+	h.algorithms = []string{manifest.MD5}
+	h.verifyOnly = true
+	report := &bytes.Buffer{}
+	h.report = reportTAP
+	h.reportOutput = report
+
+	// WHEN the folder is verified with a TAP report
+	if err := h.HashFolder(dirName); err == nil {
+		t.Error("The folder should fail")
+	}
+
+	// THEN there is a line for every file and the unknown hash
+	tap := report.String()
+	lines := []string{
+		"TAP version 13\n1..5\n",
+		"ok 1 - a.txt\n",
+		"not ok 2 - b.txt\n  ---\n  status: changed\n",
+		"  expected:\n    MD5: 92eb5ffee6ae2fec3ad71c777531578f\n  actual:\n    MD5: 8977dfac2f8e04cb96e66882235f5aba\n  ...\n",
+		"ok 3 - c.txt # new\n",
+		"not ok 4 - d.txt\n  ---\n  status: missing\n",
+		"not ok 5 - " + unknownFileName + " line 2\n",
+		"  hash: d41d8cd98f00b204e9800998ecf8427e\n",
+	}
+	for _, line := range lines {
+		if !strings.Contains(tap, line) {
+			t.Errorf("Expected %q in the report:\n%v", line, tap)
+		}
+	}
+}
+
+func Test_parseReportFormat(t *testing.T) {
+	for _, format := range []reportFormat{reportText, reportJSON, reportJUnit, reportTAP} {
+		// WHEN the name of the format is parsed
+		parsed, err := parseReportFormat(format.String())
+
+		// THEN it is the same format
+		if err != nil || parsed != format {
+			t.Errorf("Expected %v got %v %v", format, parsed, err)
+		}
+	}
+	if _, err := parseReportFormat("pdf"); err == nil {
+		t.Error("An unknown format should be an error")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"fmt"
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"strings"
+)
+
+// fileStatus is how a file compares to the previous manifest.
+type fileStatus int
+
+const (
+	statusOK       fileStatus = iota // the file matches the manifest
+	statusChanged                    // the file's hashes don't match the manifest
+	statusMetadata                   // the file's hashes match, but an attribute like its mode doesn't
+	statusNew                        // the file is in the folder, but not in the manifest
+	statusMissing                    // the file is in the manifest, but not in the folder
+	statusError                      // the file couldn't be read, so it wasn't checked
+	statusMoved                      // the file is in the manifest with a different name, but its contents are the same
+)
+
+// every status, in the order they are summarized
+var fileStatuses = []fileStatus{statusOK, statusChanged, statusMetadata, statusMoved, statusNew, statusMissing, statusError}
+
+// optionalStatuses are only in the summary when a file has them
+var optionalStatuses = map[fileStatus]bool{statusMetadata: true, statusMoved: true, statusError: true}
+
+func (s fileStatus) String() string {
+	switch s {
+	case statusOK:
+		return "ok"
+	case statusChanged:
+		return "changed"
+	case statusMetadata:
+		return "metadata"
+	case statusNew:
+		return "new"
+	case statusMissing:
+		return "missing"
+	case statusError:
+		return "error"
+	case statusMoved:
+		return "moved"
+	}
+	return fmt.Sprintf("status(%d)", int(s))
+}
+
+// fileResult is the outcome of checking one file.
+type fileResult struct {
+	FileName  string
+	Status    fileStatus
+	Expected  manifest.Sum // the sum from the previous manifest, nil if the file is new
+	Actual    manifest.Sum // the sum calculated from the file, nil if the file is missing
+	Err       error        // why the file failed, if it did
+	Cached    bool         // the file wasn't hashed because it was unchanged in the cache
+	MovedFrom string       // the name of the file in the previous manifest, if it was moved
+	Accepted  bool         // the file didn't match, but it was allowed to change
+
+	manifestName string // the name of the file in the old manifest, which depends on the path policy
+}
+
+// unknownResult is a hash from the -unknown file that didn't match any file.
+type unknownResult struct {
+	Hash     string
+	Location manifest.HashLocation
+}
+
+// verifyResult is the outcome of checking every file in a folder.
+type verifyResult struct {
+	Files   []*fileResult
+	Unknown []*unknownResult // hashes left in the -unknown file, in the order of their lines
+}
+
+// add a file result to the list
+func (r *verifyResult) add(f *fileResult) {
+	r.Files = append(r.Files, f)
+}
+
+// count how many files have the status
+func (r *verifyResult) count(status fileStatus) int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// failures counts how many files with the status failed, files that were accepted aren't counted
+func (r *verifyResult) failures(status fileStatus, strict bool) int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Status == status && f.failed(strict) {
+			count++
+		}
+	}
+	return count
+}
+
+// failed returns true if any file failed, or any unknown hash wasn't found, with `strict` new files are failures too.
+func (r *verifyResult) failed(strict bool) bool {
+	if len(r.Unknown) > 0 {
+		return true
+	}
+	for _, f := range r.Files {
+		if f.failed(strict) {
+			return true
+		}
+	}
+	return false
+}
+
+// failed returns true if the file didn't pass, with `strict` new files are failures too.
+func (f *fileResult) failed(strict bool) bool {
+	switch f.Status {
+	case statusOK:
+		return false
+	case statusNew:
+		return strict && !f.Accepted
+	}
+	return !f.Accepted
+}
+
+// summary is a one line description of how many files had each status, ex. "Checked 3 files: 2 ok, 1 changed, 0 new, 0 missing"
+func (r *verifyResult) summary() string {
+	counts := make([]string, 0, len(fileStatuses))
+	for _, status := range fileStatuses {
+		count := r.count(status)
+		if count == 0 && optionalStatuses[status] {
+			continue
+		}
+		counts = append(counts, fmt.Sprintf("%d %v", count, status))
+	}
+	if len(r.Unknown) > 0 {
+		counts = append(counts, fmt.Sprintf("%d unknown hashes not found", len(r.Unknown)))
+	}
+	if cached := r.cached(); cached > 0 {
+		return fmt.Sprintf("Checked %d files (%d unchanged in cache): %v", len(r.Files), cached, strings.Join(counts, ", "))
+	}
+	return fmt.Sprintf("Checked %d files: %v", len(r.Files), strings.Join(counts, ", "))
+}
+
+// manifestNames are the names in the previous manifest of every file that was found
+func (r *verifyResult) manifestNames() map[string]bool {
+	names := make(map[string]bool, len(r.Files))
+	for _, f := range r.Files {
+		if f.manifestName != "" {
+			names[f.manifestName] = true
+		}
+	}
+	return names
+}
+
+// errorNames are the names of every file that couldn't be read, as they are in the folder
+func (r *verifyResult) errorNames() map[string]bool {
+	names := map[string]bool{}
+	for _, f := range r.Files {
+		if f.Status == statusError {
+			names[f.FileName] = true
+		}
+	}
+	return names
+}
+
+// cached counts how many files weren't hashed because they were unchanged in the cache
+func (r *verifyResult) cached() int {
+	cached := 0
+	for _, f := range r.Files {
+		if f.Cached {
+			cached++
+		}
+	}
+	return cached
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import "testing"
+
+func Test_verifyResult_failed(t *testing.T) {
+	// GIVEN a result with an ok file and a new file
+	r := &verifyResult{}
+	r.add(&fileResult{FileName: "a.txt", Status: statusOK})
+	r.add(&fileResult{FileName: "c.txt", Status: statusNew})
+
+	// THEN it only fails when strict
+	if r.failed(false) {
+		t.Error("A new file should not fail when not strict")
+	}
+	if !r.failed(true) {
+		t.Error("A new file should fail when strict")
+	}
+
+	// WHEN a file is missing
+	r.add(&fileResult{FileName: "b.txt", Status: statusMissing})
+
+	// THEN it always fails
+	if !r.failed(false) {
+		t.Error("A missing file should fail")
+	}
+}
+
+func Test_verifyResult_summary(t *testing.T) {
+	// GIVEN a result with one of each status
+	r := &verifyResult{}
+	r.add(&fileResult{FileName: "a.txt", Status: statusOK})
+	r.add(&fileResult{FileName: "b.txt", Status: statusChanged})
+	r.add(&fileResult{FileName: "c.txt", Status: statusNew})
+	r.add(&fileResult{FileName: "d.txt", Status: statusMissing})
+
+	// WHEN it is summarized
+	s := r.summary()
+
+	// THEN every status should be counted
+	expected := "Checked 4 files: 1 ok, 1 changed, 1 new, 1 missing"
+	if s != expected {
+		t.Errorf("Expected %q got %q", expected, s)
+	}
+}
+
+func Test_verifyResult_summary_Metadata(t *testing.T) {
+	// GIVEN a result with a file whose metadata changed
+	r := &verifyResult{}
+	r.add(&fileResult{FileName: "a.txt", Status: statusOK})
+	r.add(&fileResult{FileName: "b.sh", Status: statusMetadata})
+
+	// THEN it fails, and is counted separately from changed files
+	if !r.failed(false) {
+		t.Error("Changed metadata should fail")
+	}
+	expected := "Checked 2 files: 1 ok, 0 changed, 1 metadata, 0 new, 0 missing"
+	if s := r.summary(); s != expected {
+		t.Errorf("Expected %q got %q", expected, s)
+	}
+}
+
+func Test_verifyResult_failed_Unknown(t *testing.T) {
+	// GIVEN a result where every file is ok, but an unknown hash wasn't found
+	r := &verifyResult{}
+	r.add(&fileResult{FileName: "a.txt", Status: statusOK})
+	r.Unknown = append(r.Unknown, &unknownResult{Hash: "d41d8cd98f00b204e9800998ecf8427e"})
+
+	// THEN it fails
+	if !r.failed(false) {
+		t.Error("An unknown hash that wasn't found should fail")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// symlinkPolicy is what walkFolder does with symbolic links.
+type symlinkPolicy int
+
+const (
+	symlinksFollow symlinkPolicy = iota // hash the file a link points to, and walk the folder a link points to
+	symlinksSkip                        // links are not in the manifest
+	symlinksRecord                      // the target of the link is in the manifest instead of a hash
+)
+
+// parseSymlinkPolicy returns the symlinkPolicy for a name used on the command line: "follow", "skip" or "record".
+func parseSymlinkPolicy(name string) (symlinkPolicy, error) {
+	switch strings.ToLower(name) {
+	case "", "follow":
+		return symlinksFollow, nil
+	case "skip":
+		return symlinksSkip, nil
+	case "record":
+		return symlinksRecord, nil
+	}
+	return symlinksFollow, fmt.Errorf("Unknown symlink policy %v, expected skip, record or follow", name)
+}
+
+func (p symlinkPolicy) String() string {
+	switch p {
+	case symlinksFollow:
+		return "follow"
+	case symlinksSkip:
+		return "skip"
+	case symlinksRecord:
+		return "record"
+	}
+	return fmt.Sprintf("symlinkPolicy(%d)", int(p))
+}
+
+// isSymlink returns true if the file info is for a symbolic link, and not what it points to
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// linkedFolders are the folders that links were followed to in one walk.
+// Two links to the same folder, or to folders with links to the same folder, would walk it more than once.
+type linkedFolders []os.FileInfo
+
+// visit returns true the first time a folder is visited, and false if it already was
+func (l *linkedFolders) visit(target os.FileInfo) bool {
+	for _, info := range *l {
+		if os.SameFile(info, target) {
+			return false
+		}
+	}
+	*l = append(*l, target)
+	return true
+}
+
+// isLinkLoop returns true if the folder a link points to is the folder the link is in, or one above it.
+// Following a link like that would walk forever.
+func isLinkLoop(linkPath string, target os.FileInfo) bool {
+	if abs, err := filepath.Abs(linkPath); err == nil {
+		linkPath = abs
+	}
+	for dir := filepath.Dir(linkPath); ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil && os.SameFile(info, target) {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// makeSymlinkFolder makes a folder with a link to a file, a link to a folder, and a link that loops back to the root
+func makeSymlinkFolder(t *testing.T) string {
+	dirName := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dirName, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dirName, filepath.FromSlash(name)), []byte("a"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"link.txt": "a.txt",
+		"linkdir":  "sub",
+		"sub/up":   "..",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dirName, filepath.FromSlash(name))); err != nil {
+			t.Skip("Symbolic links aren't supported:", err)
+		}
+	}
+	return dirName
+}
+
+// hashSymlinkFolder hashes the folder with the policy, and returns the names in the saved manifest
+func hashSymlinkFolder(t *testing.T, dirName string, symlinks symlinkPolicy) (manifest.Manifest, []string) {
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+	h.symlinks = symlinks
+	if err := h.HashFolder(dirName); err != nil {
+		t.Fatal(err, errorBuffer)
+	}
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return m, names
+}
+
+func Test_hashFolder_Symlinks_Skip(t *testing.T) {
+	// GIVEN a folder with links
+	dirName := makeSymlinkFolder(t)
+
+	// WHEN it is hashed skipping links
+	_, names := hashSymlinkFolder(t, dirName, symlinksSkip)
+
+	// THEN only the real files are in the manifest
+	if got := strings.Join(names, ","); got != "a.txt,sub/b.txt" {
+		t.Errorf("Expected only the real files, got %v", got)
+	}
+}
+
+func Test_hashFolder_Symlinks_Follow(t *testing.T) {
+	// GIVEN a folder with links
+	dirName := makeSymlinkFolder(t)
+
+	// WHEN it is hashed following links
+	m, names := hashSymlinkFolder(t, dirName, symlinksFollow)
+
+	// THEN the linked file and folder are hashed, but the loop isn't followed
+	if got := strings.Join(names, ","); got != "a.txt,link.txt,linkdir/b.txt,sub/b.txt" {
+		t.Errorf("Expected the linked files, got %v", got)
+	}
+	if m["link.txt"][manifest.MD5] != m["a.txt"][manifest.MD5] {
+		t.Errorf("The link should have the hash of a.txt %v", m)
+	}
+}
+
+func Test_hashFolder_Symlinks_Dangling(t *testing.T) {
+	// GIVEN a folder with a link that points nowhere
+	dirName := makeSymlinkFolder(t)
+	if err := os.Symlink("nowhere", filepath.Join(dirName, "stale")); err != nil {
+		t.Skip("Symbolic links aren't supported:", err)
+	}
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.algorithms = []string{manifest.MD5}
+
+	// WHEN the links are followed
+	err := h.HashFolder(dirName)
+
+	// THEN the link is an error, and the other files are still hashed
+	if code := exitCode(err); code != exitIO {
+		t.Errorf("Expected %d got %d: %v", exitIO, code, err)
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "Error reading stale: Couldn't follow link") {
+		t.Errorf("Expected the link to be an error: %v", es)
+	}
+	if is := infoBuffer.String(); !strings.Contains(is, "1 error") || !strings.Contains(is, "sub/b.txt") {
+		t.Errorf("Expected the other files to be hashed: %v", is)
+	}
+}
+
+func Test_hashFolder_Symlinks_Record(t *testing.T) {
+	// GIVEN a folder with links
+	dirName := makeSymlinkFolder(t)
+
+	// WHEN it is hashed recording links
+	m, names := hashSymlinkFolder(t, dirName, symlinksRecord)
+
+	// THEN the links are in the manifest with their targets
+	if got := strings.Join(names, ","); got != "a.txt,link.txt,linkdir,sub/b.txt,sub/up" {
+		t.Errorf("Expected the links, got %v", got)
+	}
+	if target := m["linkdir"][manifest.Link]; target != "sub" {
+		t.Errorf("Expected linkdir to be a link to sub, got %v", m["linkdir"])
+	}
+
+	// WHEN a link is changed to point somewhere else
+	if err := os.Remove(filepath.Join(dirName, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/b.txt", filepath.Join(dirName, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+	h.symlinks = symlinksRecord
+	err := h.HashFolder(dirName)
+
+	// THEN verification fails
+	if err == nil {
+		t.Error("A changed link should fail")
+	}
+	if es := errorBuffer.String(); !strings.Contains(es, "link mismatch a.txt != sub/b.txt") {
+		t.Errorf("Expected a link mismatch: %v", es)
+	}
+}
+
+func Test_parseSymlinkPolicy(t *testing.T) {
+	for _, policy := range []symlinkPolicy{symlinksFollow, symlinksSkip, symlinksRecord} {
+		// WHEN the name of the policy is parsed
+		parsed, err := parseSymlinkPolicy(policy.String())
+
+		// THEN it is the same policy
+		if err != nil || parsed != policy {
+			t.Errorf("Expected %v got %v %v", policy, parsed, err)
+		}
+	}
+	if _, err := parseSymlinkPolicy("sometimes"); err == nil {
+		t.Error("An unknown policy should be an error")
+	}
+}
+
+func Test_hashFolder_Symlinks_SameFolder(t *testing.T) {
+	// GIVEN two folders with links to the same folder outside the root
+	dirName := t.TempDir()
+	sharedDir := t.TempDir()
+	writeTestFiles(t, dirName, map[string]string{"a/a.txt": "a", "b/b.txt": "b"})
+	writeTestFiles(t, sharedDir, map[string]string{"x.txt": "x", "deeper/y.txt": "y"})
+	for _, name := range []string{"a/shared", "b/shared"} {
+		if err := os.Symlink(sharedDir, filepath.Join(dirName, filepath.FromSlash(name))); err != nil {
+			t.Skip("Symbolic links aren't supported:", err)
+		}
+	}
+
+	// WHEN it is hashed following links
+	_, names := hashSymlinkFolder(t, dirName, symlinksFollow)
+
+	// THEN the shared folder is only walked through the first link
+	if got := strings.Join(names, ","); got != "a/a.txt,a/shared/deeper/y.txt,a/shared/x.txt,b/b.txt" {
+		t.Errorf("Expected the shared folder once, got %v", got)
+	}
+}
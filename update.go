@@ -0,0 +1,73 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"fmt"
+)
+
+// updateCommand hashes the folder and saves the manifest even when files changed, were moved or are missing,
+// with -accept only the files that match may change, any other change still fails
+func updateCommand(h *folderHasher, dirName string, args []string) int {
+	flags := newCommandFlags(h, "update")
+	var accepts stringList
+	flags.Var(&accepts, "accept", "Pattern of files that may change, with the same rules as a line in "+ignoreFileName+".  Can be repeated.")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if h.verifyOnly || len(h.manifestFileName) == 0 {
+		h.errorLog.Print("update saves the manifest, it can't be used with -verify or without -manifest")
+		return exitUsage
+	}
+	h.update = true
+	h.accepts = accepts
+	if err := h.HashFolder(dirName); err != nil {
+		h.errorLog.Print(err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// acceptUpdates accepts every change to a file that matches the accept rules, and logs exactly what changed
+// a change that isn't accepted is logged as an error, so the manifest isn't saved
+func (h *folderHasher) acceptUpdates(result *verifyResult, accepts []*ignoreRule) {
+	for _, f := range result.Files {
+		var change string
+		switch f.Status {
+		case statusNew:
+			change = fmt.Sprintf("Added %v", f.FileName)
+		case statusChanged, statusMetadata:
+			change = fmt.Sprintf("Changed %v: %v", f.FileName, f.Err)
+		case statusMissing:
+			change = fmt.Sprintf("Removed %v", f.FileName)
+		case statusMoved:
+			change = fmt.Sprintf("Moved %v from %v", f.FileName, f.MovedFrom)
+		default:
+			continue
+		}
+		accepted := acceptedChange(accepts, f.FileName)
+		if f.Status == statusMoved {
+			// both names change, unless every move is accepted
+			accepted = h.acceptMoves || accepted && acceptedChange(accepts, f.MovedFrom)
+		}
+		f.Accepted = accepted
+		if f.failed(h.strict) {
+			h.errorLog.Printf("%v, but it doesn't match -accept\n", change)
+			continue
+		}
+		h.infoLog.Println(change)
+	}
+}
+
+// acceptedChange returns true if the file may change, every file may change without any accept rules
+func acceptedChange(accepts []*ignoreRule, fileName string) bool {
+	if len(accepts) == 0 {
+		return true
+	}
+	for _, rule := range accepts {
+		if rule.match(fileName, false) {
+			return !rule.negate
+		}
+	}
+	return false
+}
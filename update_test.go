@@ -0,0 +1,109 @@
+// Copyright (C) 2017 Robert A. Wallis, All Rights Reserved
+
+package main
+
+import (
+	"github.com/robert-wallis/VerifyManifest/manifest"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeUpdatedFolder makes a folder with a manifest, then changes a.txt, removes c.txt, adds d.txt and moves sub/b.txt
+func makeUpdatedFolder(t *testing.T) string {
+	dirName := makeMovedFolder(t)
+	if err := ioutil.WriteFile(filepath.Join(dirName, "a.txt"), []byte("updated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dirName
+}
+
+func Test_updateCommand(t *testing.T) {
+	// GIVEN a folder that changed since its manifest was saved
+	dirName := makeUpdatedFolder(t)
+	infoBuffer, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN it is updated
+	if code := updateCommand(h, dirName, nil); code != exitOK {
+		t.Fatal(code, errorBuffer)
+	}
+
+	// THEN every change is logged
+	info := infoBuffer.String()
+	for _, line := range []string{
+		"Added d.txt",
+		"Changed a.txt: ",
+		"Removed c.txt",
+		"Moved other/renamed.txt from sub/b.txt",
+	} {
+		if !strings.Contains(info, line) {
+			t.Errorf("Expected %q in %v", line, info)
+		}
+	}
+
+	// AND the manifest is saved with the new files
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["c.txt"]; ok || len(m) != 3 {
+		t.Errorf("Expected a.txt, d.txt and other/renamed.txt got %v", m)
+	}
+}
+
+func Test_updateCommand_Accept(t *testing.T) {
+	// GIVEN a folder that changed since its manifest was saved
+	dirName := makeUpdatedFolder(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN it is updated, but only a.txt may change
+	code := updateCommand(h, dirName, []string{"-accept", "/a.txt"})
+
+	// THEN the other changes fail, and the manifest isn't saved
+	if code != exitMissing {
+		t.Errorf("Expected %d got %d: %v", exitMissing, code, errorBuffer)
+	}
+	es := errorBuffer.String()
+	if !strings.Contains(es, "Removed c.txt, but it doesn't match -accept") {
+		t.Errorf("Expected c.txt not to be accepted: %v", es)
+	}
+	if strings.Contains(es, "Changed a.txt") {
+		t.Errorf("a.txt should be accepted: %v", es)
+	}
+	m := manifest.Manifest{}
+	if err := m.Load(dirName, "manifest.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["c.txt"]; !ok {
+		t.Errorf("The manifest shouldn't be updated %v", m)
+	}
+}
+
+func Test_updateCommand_AcceptAll(t *testing.T) {
+	// GIVEN a folder that changed since its manifest was saved
+	dirName := makeUpdatedFolder(t)
+	_, errorBuffer, h := makeTestFolderHasher("manifest.json", "")
+
+	// WHEN every changed file matches an accept pattern
+	code := updateCommand(h, dirName, []string{"-accept", "*.txt"})
+
+	// THEN the manifest is saved
+	if code != exitOK {
+		t.Errorf("Expected %d got %d: %v", exitOK, code, errorBuffer)
+	}
+}
+
+func Test_updateCommand_Verify(t *testing.T) {
+	// GIVEN verify only mode
+	_, _, h := makeTestFolderHasher("manifest.json", "")
+	h.verifyOnly = true
+
+	// WHEN it is updated
+	code := updateCommand(h, t.TempDir(), nil)
+
+	// THEN it's a usage error
+	if code != exitUsage {
+		t.Errorf("Expected %d got %d", exitUsage, code)
+	}
+}